@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// defaultBatchConcurrency 未设置全局并发数时使用的默认值
+const defaultBatchConcurrency = 10
+
+// BatchOptions 批量扫描选项
+type BatchOptions struct {
+	Concurrency        int      // 全局并发数，<= 0 时使用默认值 10
+	PerHostConcurrency int      // 同一主机（不区分端口）的并发上限，<= 0 表示只受全局并发数限制
+	LogLevel           LogLevel // 各引擎的日志级别
+}
+
+// RunBatch 对每个目标执行每个 POC，返回的结果按目标、POC 的顺序排列
+// 多个目标指向同一主机（如同一 CDN 后的站点）时，可以通过 PerHostConcurrency 避免集中请求同一后端
+func RunBatch(ctx context.Context, configs []*POCConfig, targets []string, opts BatchOptions) []Result {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	global := make(chan struct{}, concurrency)
+	hosts := newHostLimiter(opts.PerHostConcurrency)
+
+	results := make([]Result, len(targets)*len(configs))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		for j, config := range configs {
+			wg.Add(1)
+			go func(index int, target string, config *POCConfig) {
+				defer wg.Done()
+
+				// 先占用主机的名额再占用全局名额，等待同一主机时不占用全局并发
+				release := hosts.acquire(batchHost(target))
+				defer release()
+				global <- struct{}{}
+				defer func() { <-global }()
+
+				engine := NewEngine(config, target)
+				engine.SetLogLevel(opts.LogLevel)
+				engine.ExecuteContext(ctx)
+				results[index] = engine.Result()
+			}(i*len(configs)+j, target, config)
+		}
+	}
+	wg.Wait()
+	return results
+}
+
+// RunPOCs 对同一目标依次执行多个 POC，返回已执行 POC 的结果
+// stopOnFirstMatch 为 true 时第一个 POC 匹配后不再执行其余 POC，适合只需判断目标是否存在任一漏洞的场景；
+// 执行出错的 POC 记录在 Result.Error 中，不影响后续 POC
+func RunPOCs(configs []*POCConfig, target string, stopOnFirstMatch bool) []Result {
+	return RunPOCsContext(context.Background(), configs, target, stopOnFirstMatch)
+}
+
+// RunPOCsContext 使用指定的 context 依次执行多个 POC，context 取消后不再执行其余 POC
+func RunPOCsContext(ctx context.Context, configs []*POCConfig, target string, stopOnFirstMatch bool) []Result {
+	results := make([]Result, 0, len(configs))
+	for _, config := range configs {
+		if ctx.Err() != nil {
+			break
+		}
+		engine := NewEngine(config, target)
+		matched, _ := engine.ExecuteContext(ctx)
+		results = append(results, engine.Result())
+		if matched && stopOnFirstMatch {
+			break
+		}
+	}
+	return results
+}
+
+// hostLimiter 按主机限制并发
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	sems  map[string]chan struct{}
+}
+
+// newHostLimiter 创建主机并发限制，limit <= 0 时不限制
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, sems: make(map[string]chan struct{})}
+}
+
+// acquire 占用主机的一个名额，返回释放函数
+func (h *hostLimiter) acquire(host string) func() {
+	if h.limit <= 0 {
+		return func() {}
+	}
+	h.mu.Lock()
+	sem, ok := h.sems[host]
+	if !ok {
+		sem = make(chan struct{}, h.limit)
+		h.sems[host] = sem
+	}
+	h.mu.Unlock()
+
+	sem <- struct{}{}
+	return func() { <-sem }
+}
+
+// batchHost 返回目标的主机名（小写，不含端口），无法解析时返回原始目标
+func batchHost(target string) string {
+	raw := target
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return target
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,135 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBatchPerHostConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	active := map[string]int{}
+	maxPerHost := map[string]int{}
+	total, maxTotal := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		active[host]++
+		total++
+		if active[host] > maxPerHost[host] {
+			maxPerHost[host] = active[host]
+		}
+		if total > maxTotal {
+			maxTotal = total
+		}
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		active[host]--
+		total--
+		mu.Unlock()
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// 127.0.0.1 和 localhost 指向同一服务器，但按主机名分别限制
+	var targets []string
+	for _, host := range []string{"127.0.0.1", "localhost"} {
+		for _, path := range []string{"/a", "/b", "/c"} {
+			targets = append(targets, "http://"+host+":"+port+path)
+		}
+	}
+	config := mustLoadConfig(t, `
+name: batch
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+expression: r0()
+`)
+
+	results := RunBatch(context.Background(), []*POCConfig{config}, targets, BatchOptions{Concurrency: 4, PerHostConcurrency: 1})
+	if len(results) != len(targets) {
+		t.Fatalf("结果数 = %d，期望 %d", len(results), len(targets))
+	}
+	for i, r := range results {
+		if !r.Matched {
+			t.Errorf("目标 %s 未匹配: %+v", targets[i], r)
+		}
+	}
+	if maxPerHost["127.0.0.1"] != 1 || maxPerHost["localhost"] != 1 {
+		t.Errorf("同一主机的最大并发 = %v，期望 1", maxPerHost)
+	}
+	if maxTotal != 2 {
+		t.Errorf("全部主机的最大并发 = %d，期望不同主机并行（2）", maxTotal)
+	}
+}
+
+func TestBatchHost(t *testing.T) {
+	for target, want := range map[string]string{
+		"http://Example.com:8080/app": "example.com",
+		"https://example.com":         "example.com",
+		"example.com:443":             "example.com",
+		"http://[::1]:8080":           "::1",
+		"://bad":                      "://bad",
+	} {
+		if got := batchHost(target); got != want {
+			t.Errorf("batchHost(%q) = %q，期望 %q", target, got, want)
+		}
+	}
+}
+
+func TestRunPOCsStopOnFirstMatch(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/second" {
+			w.Write([]byte("vulnerable"))
+		}
+	}))
+	defer server.Close()
+
+	var configs []*POCConfig
+	for _, name := range []string{"first", "second", "third"} {
+		configs = append(configs, mustLoadConfig(t, `
+name: `+name+`
+rules:
+  r0:
+    method: GET
+    path: /`+name+`
+    expression: response.body.contains('vulnerable')
+expression: r0()
+`))
+	}
+
+	results := RunPOCs(configs, server.URL, true)
+	if len(results) != 2 || results[0].Matched || !results[1].Matched || results[1].Name != "second" {
+		t.Errorf("stopOnFirstMatch 的结果 = %+v，期望在第二个 POC 匹配后停止", results)
+	}
+	if !reflect.DeepEqual(paths, []string{"/first", "/second"}) {
+		t.Errorf("请求路径 = %v，第三个 POC 不应执行", paths)
+	}
+
+	paths = nil
+	results = RunPOCs(configs, server.URL, false)
+	if len(results) != 3 || !results[1].Matched || results[2].Matched {
+		t.Errorf("不停止时的结果 = %+v，期望执行全部 POC", results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results := RunPOCsContext(ctx, configs, server.URL, false); len(results) != 0 {
+		t.Errorf("context 已取消时执行了 %d 个 POC", len(results))
+	}
+}
@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// Chain 依次执行多个 POC，共享变量上下文和 Cookie，用于多阶段利用：
+// 前面 POC 提取的变量（如 token）和 Cookie 可以在后面的 POC 中直接引用
+type Chain struct {
+	configs           []*POCConfig
+	baseURL           string
+	httpClient        *HTTPClient
+	evaluator         *ExpressionEvaluator
+	continueOnFailure bool
+	logLevel          LogLevel
+	results           []Result
+}
+
+// NewChain 创建 POC 链
+func NewChain(baseURL string, configs ...*POCConfig) *Chain {
+	return &Chain{
+		configs:    configs,
+		baseURL:    baseURL,
+		httpClient: NewHTTPClient(baseURL),
+		evaluator:  NewExpressionEvaluator(),
+	}
+}
+
+// SetContinueOnFailure 设置某个 POC 未匹配时是否继续执行后续 POC，默认停止
+func (c *Chain) SetContinueOnFailure(continueOnFailure bool) {
+	c.continueOnFailure = continueOnFailure
+}
+
+// SetVerbose 设置详细输出模式，true 相当于 SetLogLevel(LogDebug)
+func (c *Chain) SetVerbose(verbose bool) {
+	c.SetLogLevel(verboseLevel(verbose))
+}
+
+// SetLogLevel 设置日志级别
+func (c *Chain) SetLogLevel(level LogLevel) {
+	c.logLevel = level
+	c.httpClient.SetLogLevel(level)
+}
+
+// HTTPClient 获取链中共用的 HTTP 客户端
+func (c *Chain) HTTPClient() *HTTPClient {
+	return c.httpClient
+}
+
+// Execute 依次执行所有 POC，全部匹配时返回 true
+func (c *Chain) Execute() (bool, error) {
+	return c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext 使用指定的 context 依次执行所有 POC
+// 出错时立即停止；某个 POC 未匹配时按 SetContinueOnFailure 的设置停止或继续
+func (c *Chain) ExecuteContext(ctx context.Context) (bool, error) {
+	c.results = nil
+	matched := true
+	for i, config := range c.configs {
+		engine := NewEngine(config, c.baseURL)
+		engine.httpClient = c.httpClient
+		engine.evaluator = c.evaluator
+		engine.SetLogLevel(c.logLevel)
+
+		ok, err := engine.ExecuteContext(ctx)
+		c.results = append(c.results, engine.Result())
+		if err != nil {
+			return false, fmt.Errorf("执行第 %d 个 POC %s 失败: %w", i+1, config.Name, err)
+		}
+		if !ok {
+			matched = false
+			if !c.continueOnFailure {
+				return false, nil
+			}
+		}
+	}
+	return matched, nil
+}
+
+// Results 返回最近一次执行中各 POC 的结果，按执行顺序排列，未执行的 POC 不包含在内
+func (c *Chain) Results() []Result {
+	return c.results
+}
@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChainPassesTokenToNextPOC(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"t0k3n"}`))
+		case "/admin":
+			if r.Header.Get("Authorization") == "Bearer t0k3n" && r.Header.Get("Cookie") == "t0k3n" {
+				w.Write([]byte("welcome admin"))
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// 提取表达式的参数中不能出现引号，用 ... 匹配 JSON 中的 ":"
+	login := mustLoadConfig(t, `
+name: login
+rules:
+  r0:
+    method: POST
+    path: /login
+    extract_cookie: response.body.extract('token...(?P<token>\w+)')
+    expression: response.status == 200
+expression: r0()
+`)
+	exploit := mustLoadConfig(t, `
+name: exploit
+rules:
+  r0:
+    method: GET
+    path: /admin
+    bearer_token: "{{token}}"
+    use_cookie: response.extracted_cookie
+    expression: response.body.contains('welcome')
+expression: r0()
+`)
+	missing := mustLoadConfig(t, `
+name: missing
+rules:
+  r0:
+    method: GET
+    path: /missing
+    expression: response.status == 200
+expression: r0()
+`)
+
+	chain := NewChain(server.URL, login, exploit)
+	matched, err := chain.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v，服务器收到的请求: %v", matched, err, paths)
+	}
+	if results := chain.Results(); len(results) != 2 || results[0].Name != "login" || !results[1].Matched {
+		t.Errorf("Results() = %+v", results)
+	}
+
+	// 默认在未匹配的 POC 处停止
+	paths = nil
+	chain = NewChain(server.URL, login, missing, exploit)
+	if matched, err := chain.Execute(); err != nil || matched {
+		t.Fatalf("Execute() = %v, %v，期望不匹配", matched, err)
+	}
+	if len(chain.Results()) != 2 || len(paths) != 2 {
+		t.Errorf("未匹配后应停止: 结果 %d 个，请求 %v", len(chain.Results()), paths)
+	}
+
+	paths = nil
+	chain.SetContinueOnFailure(true)
+	if matched, err := chain.Execute(); err != nil || matched {
+		t.Fatalf("Execute() = %v, %v，期望不匹配", matched, err)
+	}
+	if results := chain.Results(); len(results) != 3 || !results[2].Matched {
+		t.Errorf("继续执行时结果 = %+v，请求 %v", results, paths)
+	}
+}
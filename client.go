@@ -2,54 +2,401 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 // HTTPClient HTTP 客户端包装
 type HTTPClient struct {
 	client       *http.Client
+	transport    *http.Transport // 所有请求共用的传输层，复用连接
+	dialer       *net.Dialer
+	resolve      map[string]string // 主机名到 IP 的解析覆盖
+	unixSocket   string            // 非空时所有连接都通过该 Unix 套接字建立
 	baseURL      string
 	cookies      map[string]string // 存储提取的 Cookie
 	skipTLSVerify bool             // 跳过 TLS 验证（仅用于测试）
-	verbose      bool               // 详细输出
+	logLevel     LogLevel           // 日志级别
+	requestHook  func(*http.Request) // 发送前调用，可修改请求
+	signer       func(*http.Request) error // 在请求钩子之后调用，用于对最终请求签名
+	responseHook func(*Response)     // 收到响应后调用
+	cacheEnabled bool                 // 是否缓存相同请求的响应
+	cache        map[string]*Response // 请求特征到响应的缓存
+	cacheMu      sync.Mutex
+	maxRetryAfter time.Duration // 按 Retry-After 等待的最长时间
+	blockInsecureRedirect bool // 不跟随 https→http 的降级跳转
+	maxRequests  int64 // 请求数上限（含重试），0 表示不限制
+	requestCount int64 // 已发送的请求数
 }
 
 // NewHTTPClient 创建新的 HTTP 客户端
 func NewHTTPClient(baseURL string) *HTTPClient {
 	// 配置 TLS，默认跳过验证（仅用于测试环境）
+	// 传输层在多次请求间复用，避免每条规则都重新建立连接和 TLS 握手
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
 	}
 
-	return &HTTPClient{
+	c := &HTTPClient{
+		// 超时由每个请求的 context 控制
 		client: &http.Client{
-			Timeout:   60 * time.Second, // 增加默认超时到 60 秒
 			Transport: tr,
 		},
-		baseURL:       baseURL,
+		transport:     tr,
+		dialer:        &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		resolve:       make(map[string]string),
 		cookies:       make(map[string]string),
 		skipTLSVerify: true, // 默认跳过 TLS 验证
-		verbose:       false,
+		maxRetryAfter: defaultMaxRetryAfter,
+	}
+	tr.DialContext = c.dialContext
+	c.client.Transport = &rawHeaderTransport{client: c, next: tr}
+	c.SetBaseURL(baseURL)
+	return c
+}
+
+// Resolve 将主机名固定解析到指定 IP，请求的 Host 头和 TLS SNI 仍使用原主机名
+func (c *HTTPClient) Resolve(host, ip string) {
+	c.resolve[strings.ToLower(host)] = ip
+}
+
+// SetMaxRequests 设置请求数上限，重试和载荷迭代的每次请求都计入，超过后返回 ErrMaxRequestsExceeded
+// n <= 0 表示不限制
+func (c *HTTPClient) SetMaxRequests(n int) {
+	atomic.StoreInt64(&c.maxRequests, int64(n))
+}
+
+// ResetRequestCount 将已发送的请求数清零
+func (c *HTTPClient) ResetRequestCount() {
+	atomic.StoreInt64(&c.requestCount, 0)
+}
+
+// RequestCount 返回已发送的请求数
+func (c *HTTPClient) RequestCount() int {
+	return int(atomic.LoadInt64(&c.requestCount))
+}
+
+// acquireRequest 计数一次请求，超过上限时返回错误
+func (c *HTTPClient) acquireRequest() error {
+	max := atomic.LoadInt64(&c.maxRequests)
+	count := atomic.AddInt64(&c.requestCount, 1)
+	if max > 0 && count > max {
+		atomic.AddInt64(&c.requestCount, -1)
+		return fmt.Errorf("%w（上限 %d）", ErrMaxRequestsExceeded, max)
 	}
+	return nil
+}
+
+// SetMaxRetryAfter 设置按 Retry-After 响应头等待的最长时间，超过时按该时间等待
+func (c *HTTPClient) SetMaxRetryAfter(d time.Duration) {
+	c.maxRetryAfter = d
+}
+
+// SetUnixSocket 通过 Unix 套接字发送请求（如 /var/run/docker.sock），请求路径和 Host 头不变
+// 传入空字符串恢复为 TCP 连接
+func (c *HTTPClient) SetUnixSocket(path string) {
+	if path == c.unixSocket {
+		return
+	}
+	c.unixSocket = path
+	// 已建立的连接仍指向原来的地址，关闭后按新的设置重新连接
+	c.transport.CloseIdleConnections()
+}
+
+// dialContext 建立连接，优先使用 Unix 套接字和 Resolve 设置的解析结果
+func (c *HTTPClient) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.unixSocket != "" {
+		return c.dialer.DialContext(ctx, "unix", c.unixSocket)
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip, ok := c.resolve[strings.ToLower(host)]; ok {
+			addr = net.JoinHostPort(ip, port)
+		}
+	}
+	return c.dialer.DialContext(ctx, network, addr)
 }
 
 // SetVerbose 设置详细输出模式
 func (c *HTTPClient) SetVerbose(verbose bool) {
-	c.verbose = verbose
+	c.SetLogLevel(verboseLevel(verbose))
+}
+
+// SetLogLevel 设置日志级别，LogDebug 输出请求行和响应状态，LogTrace 另外输出完整的请求和响应体
+func (c *HTTPClient) SetLogLevel(level LogLevel) {
+	c.logLevel = level
+}
+
+// SetKeepAlive 设置是否启用连接复用（keep-alive）
+func (c *HTTPClient) SetKeepAlive(enabled bool) {
+	c.transport.DisableKeepAlives = !enabled
+}
+
+// SetIdleConnTimeout 设置空闲连接的保持时间
+func (c *HTTPClient) SetIdleConnTimeout(timeout time.Duration) {
+	c.transport.IdleConnTimeout = timeout
+}
+
+// SetDisableCompression 关闭自动解压，响应体保留服务端返回的原始字节（如 gzip 数据）
+// 关闭后不再自动添加 Accept-Encoding: gzip，Content-Encoding 响应头也会保留
+func (c *HTTPClient) SetDisableCompression(disabled bool) {
+	c.transport.DisableCompression = disabled
+}
+
+// SetMaxIdleConns 设置最大空闲连接数
+func (c *HTTPClient) SetMaxIdleConns(n int) {
+	c.transport.MaxIdleConns = n
+}
+
+// SetTLSVersions 设置 TLS 协议版本范围（如 tls.VersionTLS10、tls.VersionTLS11），0 表示使用默认值
+// 用于检测目标是否仍接受旧版本协议：握手失败时请求返回错误，成功时可通过 response.tls.version 查看协商的版本
+func (c *HTTPClient) SetTLSVersions(min, max uint16) {
+	c.transport.TLSClientConfig.MinVersion = min
+	c.transport.TLSClientConfig.MaxVersion = max
+	// 已建立的连接使用旧的配置，关闭后按新配置重新握手
+	c.transport.CloseIdleConnections()
+}
+
+// SetCipherSuites 设置 TLS 1.2 及以下版本可用的加密套件（如 tls.TLS_RSA_WITH_RC4_128_SHA），nil 表示使用默认值
+// TLS 1.3 的加密套件不可配置，检测弱加密套件时通常需要同时用 SetTLSVersions 将最高版本限制为 TLS 1.2
+func (c *HTTPClient) SetCipherSuites(suites []uint16) {
+	c.transport.TLSClientConfig.CipherSuites = suites
+	c.transport.CloseIdleConnections()
+}
+
+// SetRequestHook 设置请求钩子，在发送前调用，可用于添加签名头等修改
+func (c *HTTPClient) SetRequestHook(hook func(*http.Request)) {
+	c.requestHook = hook
+}
+
+// SetSigner 设置请求签名函数（如 AWS SigV4、HMAC），在请求头、Cookie、请求体和请求钩子都处理完之后、发送之前调用
+// 签名函数可以读取请求体，读取后会重新设置，不影响发送；返回错误时请求失败且不重试
+func (c *HTTPClient) SetSigner(signer func(*http.Request) error) {
+	c.signer = signer
+}
+
+// SetResponseHook 设置响应钩子，在响应读取完成后调用
+func (c *HTTPClient) SetResponseHook(hook func(*Response)) {
+	c.responseHook = hook
 }
 
 // Response 响应结构
 type Response struct {
 	Status  int
-	Headers map[string][]string
+	StatusText string // 状态行中的原因短语，如 "I'm a teapot"
+	Headers http.Header // 规范化的响应头，使用 Get/Values 进行不区分大小写的查找
 	Body    string
 	Cookies []*http.Cookie
+	Duration time.Duration // 请求耗时
+	TransferEncoding []string // 响应的传输编码，如 ["chunked"]
+	ContentLength int64 // 响应声明的 Content-Length，HEAD 请求没有响应体时同样有效，未知时为 -1
+	RequestDump string // 实际发出的原始请求（请求行、请求头和请求体），变量和 Cookie 均已替换
+	Timings RequestTimings // 各阶段耗时
+	TLS *tls.ConnectionState // HTTPS 连接的 TLS 信息（含对端证书），HTTP 请求时为 nil
+	URL string // 产生该响应的请求地址，跟随重定向时为最后一跳的地址
+	Streamed bool // 响应体经流式扫描，Body 为空
+	Markers  map[string]bool // 流式扫描时各标记是否出现
+	BodySize int64 // 读取的响应体字节数（解压后、字符集转换前）
+	Trailers http.Header // 响应体之后发送的 HTTP trailer（如 gRPC-web 的 grpc-status），读完响应体后才有值
+	InsecureRedirect string // 被 SetBlockInsecureRedirect 阻止的 https→http 跳转目标，没有时为空
+
+	json *jsonCache // 解析后的响应体，首次使用时填充
+}
+
+// jsonCache 缓存响应体的 JSON 解析结果
+type jsonCache struct {
+	once sync.Once
+	data interface{}
+	err  error
+}
+
+// RequestTimings 请求各阶段的耗时，复用连接时 DNS、Connect、TLSHandshake 为 0
+type RequestTimings struct {
+	DNS          time.Duration // DNS 解析
+	Connect      time.Duration // TCP 建连
+	TLSHandshake time.Duration // TLS 握手
+	FirstByte    time.Duration // 从开始发送到收到响应首字节
+}
+
+// httpClientFor 按请求选项返回使用的 http.Client，限制重定向次数时使用共享传输层的副本
+// 开启 SetBlockInsecureRedirect 时同样使用副本，在 https→http 跳转处停止
+func (c *HTTPClient) httpClientFor(opts RequestOptions) *http.Client {
+	if opts.MaxRedirects == nil && !c.blockInsecureRedirect {
+		return c.client
+	}
+	client := *c.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if c.blockInsecureRedirect && via[len(via)-1].URL.Scheme == "https" && req.URL.Scheme == "http" {
+			if blocked, ok := req.Context().Value(insecureRedirectKey{}).(*string); ok {
+				*blocked = req.URL.String()
+			}
+			return http.ErrUseLastResponse
+		}
+		if opts.MaxRedirects == nil {
+			// 与默认策略一致，最多跟随 10 次
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		}
+		// via 为已发送的请求，超过上限时返回最后一个重定向响应
+		if len(via) > *opts.MaxRedirects {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	return &client
+}
+
+// sign 调用签名函数，之后按 GetBody 重新设置请求体，签名时读取过的请求体仍能完整发送
+func (c *HTTPClient) sign(req *http.Request) error {
+	if err := c.signer(req); err != nil {
+		return fmt.Errorf("请求签名失败: %w", err)
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("请求签名后重置请求体失败: %w", err)
+		}
+		req.Body = body
+	}
+	return nil
+}
+
+// insecureRedirectKey 请求 context 中记录被阻止的 https→http 跳转目标
+type insecureRedirectKey struct{}
+
+// SetBlockInsecureRedirect 设置是否阻止从 https 跳转到 http 的降级重定向
+// 开启后遇到降级跳转时不再跟随，返回该重定向响应，并在 Response.InsecureRedirect 中记录跳转目标
+func (c *HTTPClient) SetBlockInsecureRedirect(block bool) {
+	c.blockInsecureRedirect = block
+}
+
+// containsAny 返回响应体中包含的第一个标记，流式扫描的响应使用扫描结果
+func (r *Response) containsAny(markers []string) (string, bool) {
+	for _, m := range markers {
+		if m == "" {
+			continue
+		}
+		if r.Streamed && r.Markers[m] || !r.Streamed && strings.Contains(r.Body, m) {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// BodyContains 判断响应体是否包含 text，流式扫描的响应只能查询扫描过的标记
+func (r *Response) BodyContains(text string) (bool, error) {
+	if !r.Streamed {
+		return strings.Contains(r.Body, text), nil
+	}
+	found, ok := r.Markers[text]
+	if !ok {
+		return false, fmt.Errorf("响应体为流式扫描，未扫描标记: %s", text)
+	}
+	return found, nil
+}
+
+// JSON 将响应体解析为 JSON 对象，解析结果在首次调用后缓存，表达式中的 JSON 路径取值也使用该结果
+// 响应体不是 JSON 或顶层不是对象时返回错误
+func (r *Response) JSON() (map[string]interface{}, error) {
+	data, err := r.decodedJSON()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("响应体的 JSON 顶层不是对象")
+	}
+	return obj, nil
+}
+
+// decodedJSON 返回解析后的响应体，顶层可以是任意 JSON 值
+func (r *Response) decodedJSON() (interface{}, error) {
+	if r.json == nil {
+		return decodeJSON(r.Body)
+	}
+	r.json.once.Do(func() {
+		r.json.data, r.json.err = decodeJSON(r.Body)
+	})
+	return r.json.data, r.json.err
+}
+
+// bodyPrefix 返回只保留响应体前 n 个字节的响应副本，响应体不超过 n 字节时返回自身
+func (r *Response) bodyPrefix(n int) *Response {
+	if n <= 0 || len(r.Body) <= n {
+		return r
+	}
+	prefix := *r
+	prefix.Body = r.Body[:n]
+	prefix.json = &jsonCache{}
+	return &prefix
+}
+
+// decodeBody 将响应体从 Content-Type 声明的字符集（如 gbk）转换为 UTF-8
+// 未声明、已是 UTF-8、字符集未知或转换失败时返回原始字节
+func decodeBody(body []byte, contentType string) []byte {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body
+	}
+	charset := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if charset == "" || charset == "utf-8" || charset == "utf8" {
+		return body
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return body
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// newTimingTrace 创建记录各阶段耗时的 ClientTrace
+func newTimingTrace(t *RequestTimings) *httptrace.ClientTrace {
+	var dnsStart, connectStart, tlsStart, start time.Time
+	return &httptrace.ClientTrace{
+		GetConn:  func(string) { start = time.Now() },
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.DNS = time.Since(dnsStart) },
+		ConnectStart: func(string, string) {
+			connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.Connect = time.Since(connectStart)
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.TLSHandshake = time.Since(tlsStart)
+		},
+		GotFirstResponseByte: func() { t.FirstByte = time.Since(start) },
+	}
 }
 
 // RequestOptions 请求选项
@@ -59,48 +406,92 @@ type RequestOptions struct {
 	Headers     map[string]string
 	Body        string
 	UseCookie   string
+	Host        string // 覆盖 Host 头（直接设置 req.Host）
+	Chunked     bool   // 使用 Transfer-Encoding: chunked 发送请求体
 	Timeout     time.Duration
 	RetryCount  int
+	RetryOnBodyContains []string // 响应体包含其中任一标记时视为临时失败并重试（如 "请稍后再试" 的中间页）
+	RetryOnStatus []int // 响应状态码为其中之一时视为临时失败并重试（如 429、503），有 Retry-After 头时按其等待
+	MaxRedirects *int // 最多跟随的重定向次数，0 表示不跟随，nil 时使用默认策略（最多 10 次）
+	NoCache     bool   // 开启响应缓存时仍然发送该请求（结果也不写入缓存）
+	NoCookies   bool   // 不发送存储的 Cookie，忽略 UseCookie
+	StreamMarkers []string // 非空时流式扫描响应体中的这些标记，不保留响应体，结果记录在 Response.Markers
+	BasicAuth   *BasicAuth // 生成 Basic 认证头，显式设置的 Authorization 头优先
+	BearerToken string     // 生成 Bearer 认证头，显式设置的 Authorization 头优先
+	RawHeaders  []string   // "Name: value" 形式的请求头，按原样的大小写和顺序写在其余请求头之前
 }
 
 // ExecuteRequest 执行 HTTP 请求
 func (c *HTTPClient) ExecuteRequest(opts RequestOptions) (*Response, error) {
+	return c.ExecuteRequestContext(context.Background(), opts)
+}
+
+// ExecuteRequestContext 在给定 context 下执行 HTTP 请求，context 取消或超时后不再重试
+func (c *HTTPClient) ExecuteRequestContext(parent context.Context, opts RequestOptions) (*Response, error) {
 	var lastErr error
 	
 	// 处理 URL 拼接
-	url := c.baseURL
-	// 移除 baseURL 末尾的斜杠
-	url = strings.TrimSuffix(url, "/")
-	// 确保 path 以 / 开头
-	path := opts.Path
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
+	url, err := joinURL(c.baseURL, opts.Path)
+	if err != nil {
+		return nil, err
 	}
-	url += path
 
 	// 确保超时时间至少 60 秒（用于 HTTPS/TLS）
 	if opts.Timeout < 60*time.Second {
 		opts.Timeout = 60 * time.Second
 	}
 
-	if c.verbose {
+	if c.logLevel >= LogDebug {
 		log.Printf("[请求] %s %s (超时: %v, 重试: %d)", opts.Method, url, opts.Timeout, opts.RetryCount)
 	}
 
-	// 创建带 TLS 配置和超时的传输层
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: c.skipTLSVerify,
-		},
+	if opts.NoCookies {
+		opts.UseCookie = ""
+	}
+
+	// GET、HEAD 也会发送请求体（如 Elasticsearch 的查询接口），请求体为 JSON 且未设置 Content-Type 时补上
+	if opts.Body != "" && bodylessMethod(opts.Method) {
+		if c.logLevel >= LogDebug {
+			log.Printf("[警告] %s 请求携带请求体，部分服务器或代理可能会忽略", opts.Method)
+		}
+		if json.Valid([]byte(opts.Body)) {
+			opts.Headers = withDefaultHeader(opts.Headers, "Content-Type", "application/json")
+		}
+	}
+	cookie := opts.UseCookie
+	if cookie == "response.extracted_cookie" {
+		cookie = c.GetStoredCookie()
+	}
+	key := cacheKey(url, cookie, opts)
+	if opts.NoCache {
+		key = ""
+	}
+	if resp, ok := c.cachedResponse(key); ok {
+		if c.logLevel >= LogDebug {
+			log.Printf("[缓存] 使用已缓存的响应: %s %s", opts.Method, url)
+		}
+		return resp, nil
 	}
 
+	var retryAfter time.Duration // 上一次响应要求的等待时间
 	for i := 0; i <= opts.RetryCount; i++ {
 		if i > 0 {
 			delay := time.Second * time.Duration(i*2) // 递增重试延迟
-			if c.verbose {
+			if retryAfter > 0 {
+				delay, retryAfter = retryAfter, 0
+			}
+			if c.logLevel >= LogDebug {
 				log.Printf("[重试] 等待 %v 后重试 (第 %d/%d 次)", delay, i, opts.RetryCount)
 			}
-			time.Sleep(delay)
+			select {
+			case <-time.After(delay):
+			case <-parent.Done():
+				return nil, fmt.Errorf("请求已取消: %w", parent.Err())
+			}
+		}
+
+		if err := c.acquireRequest(); err != nil {
+			return nil, err
 		}
 
 		// 创建请求体
@@ -109,21 +500,53 @@ func (c *HTTPClient) ExecuteRequest(opts RequestOptions) (*Response, error) {
 			bodyReader = bytes.NewBufferString(opts.Body)
 		}
 
-		// 创建请求
-		req, err := http.NewRequest(opts.Method, url, bodyReader)
+		// 创建请求，超时由 context 控制
+		ctx, cancel := context.WithTimeout(parent, opts.Timeout)
+		req, err := http.NewRequestWithContext(ctx, opts.Method, url, bodyReader)
 		if err != nil {
+			cancel()
 			lastErr = fmt.Errorf("创建请求失败: %w", err)
-			if c.verbose {
+			if c.logLevel >= LogDebug {
 				log.Printf("[错误] %v", lastErr)
 			}
 			continue
 		}
 
+		// 显式设置请求体长度，或按要求使用分块编码（没有请求体时忽略）
+		if opts.Chunked && opts.Body != "" {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		} else {
+			req.ContentLength = int64(len(opts.Body))
+		}
+
+		// 设置认证头，随后设置的显式请求头可以覆盖
+		if opts.BasicAuth != nil {
+			req.SetBasicAuth(opts.BasicAuth.User, opts.BasicAuth.Pass)
+		} else if opts.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+		}
+
 		// 设置请求头
 		for k, v := range opts.Headers {
 			req.Header.Set(k, v)
 		}
 
+		// 原始请求头保持名称的大小写和顺序，由 rawHeaderTransport 自行写出
+		if len(opts.RawHeaders) > 0 {
+			names, err := parseRawHeaders(opts.RawHeaders, req.Header)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			req = req.WithContext(context.WithValue(req.Context(), rawHeadersKey{}, names))
+		}
+
+		// Host 头需要通过 req.Host 设置，Header.Set("Host") 不会生效
+		if opts.Host != "" {
+			req.Host = opts.Host
+		}
+
 		// 处理 Cookie
 		if opts.UseCookie != "" {
 			// 如果 use_cookie 是特殊标识，使用提取的 Cookie
@@ -139,61 +562,326 @@ func (c *HTTPClient) ExecuteRequest(opts RequestOptions) (*Response, error) {
 			}
 		}
 
-		// 创建带超时和 TLS 配置的客户端
-		client := &http.Client{
-			Timeout:   opts.Timeout,
-			Transport: tr,
+		if c.requestHook != nil {
+			c.requestHook(req)
+		}
+
+		if c.signer != nil {
+			if err := c.sign(req); err != nil {
+				cancel()
+				return nil, err
+			}
+		}
+
+		// 在钩子之后转储请求，记录的即是最终发出的内容
+		dump, err := httputil.DumpRequestOut(req, true)
+		if err != nil {
+			cancel()
+			lastErr = fmt.Errorf("转储请求失败: %w", err)
+			if c.logLevel >= LogDebug {
+				log.Printf("[错误] %v", lastErr)
+			}
+			continue
 		}
 
 		// 执行请求
 		startTime := time.Now()
-		if c.verbose {
+		if c.logLevel >= LogDebug {
 			log.Printf("[发送] 开始发送请求到 %s", url)
 		}
 
-		resp, err := client.Do(req)
+		var timings RequestTimings
+		var insecureRedirect string
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), newTimingTrace(&timings)))
+		req = req.WithContext(context.WithValue(req.Context(), insecureRedirectKey{}, &insecureRedirect))
+
+		resp, err := c.httpClientFor(opts).Do(req)
 		duration := time.Since(startTime)
 
 		if err != nil {
-			lastErr = fmt.Errorf("请求失败 (耗时: %v): %w", duration, err)
-			if c.verbose {
+			cancel()
+			if kind := classifyError(err); kind != nil {
+				lastErr = fmt.Errorf("请求失败 (耗时: %v): %w: %w", duration, kind, err)
+			} else {
+				lastErr = fmt.Errorf("请求失败 (耗时: %v): %w", duration, err)
+			}
+			if c.logLevel >= LogDebug {
 				log.Printf("[错误] %v", lastErr)
 			}
 			continue
 		}
-		defer resp.Body.Close()
 
-		if c.verbose {
+		if c.logLevel >= LogDebug {
 			log.Printf("[响应] 状态码: %d, 耗时: %v", resp.StatusCode, duration)
+			log.Printf("[耗时] DNS: %v, 建连: %v, TLS 握手: %v, 首字节: %v",
+				timings.DNS, timings.Connect, timings.TLSHandshake, timings.FirstByte)
 		}
 
-		// 读取响应体
-		bodyBytes, err := io.ReadAll(resp.Body)
+		// 读取响应体（HEAD 请求的响应体为空，读取不会出错）
+		// 设置了流式标记时只扫描标记，不保留响应体
+		var bodyBytes []byte
+		var markers map[string]bool
+		var bodySize int64
+		if len(opts.StreamMarkers) > 0 {
+			scan := append(append([]string{}, opts.StreamMarkers...), opts.RetryOnBodyContains...)
+			markers, bodySize, err = scanMarkers(resp.Body, scan)
+		} else {
+			bodyBytes, err = io.ReadAll(resp.Body)
+			bodySize = int64(len(bodyBytes))
+		}
+		resp.Body.Close()
+		cancel()
 		if err != nil {
 			lastErr = fmt.Errorf("读取响应体失败: %w", err)
-			if c.verbose {
+			if c.logLevel >= LogDebug {
 				log.Printf("[错误] %v", lastErr)
 			}
 			continue
 		}
 
-		if c.verbose {
-			log.Printf("[响应] 响应体大小: %d 字节", len(bodyBytes))
+		if c.logLevel >= LogDebug {
+			log.Printf("[响应] 响应体大小: %d 字节", bodySize)
+		}
+		if c.logLevel >= LogTrace {
+			log.Printf("[请求内容]\n%s", dump)
+			log.Printf("[响应内容]\n%s", bodyBytes)
+		}
+
+		// 按 Content-Type 声明的字符集转为 UTF-8，便于用中文等字面量匹配（仍是压缩数据时不处理）
+		if resp.Header.Get("Content-Encoding") == "" {
+			bodyBytes = decodeBody(bodyBytes, resp.Header.Get("Content-Type"))
 		}
 
 		response := &Response{
 			Status:  resp.StatusCode,
+			StatusText: strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
 			Headers: resp.Header,
 			Body:    string(bodyBytes),
 			Cookies: resp.Cookies(),
+			Duration: duration,
+			TransferEncoding: resp.TransferEncoding,
+			ContentLength: contentLength(resp),
+			RequestDump: string(dump),
+			Timings: timings,
+			TLS: resp.TLS,
+			URL: resp.Request.URL.String(),
+			Streamed: markers != nil,
+			Markers:  markers,
+			BodySize: bodySize,
+			Trailers: resp.Trailer,
+			InsecureRedirect: insecureRedirect,
+			json:     &jsonCache{},
+		}
+
+		if c.responseHook != nil {
+			c.responseHook(response)
+		}
+
+		if marker, ok := response.containsAny(opts.RetryOnBodyContains); ok {
+			lastErr = fmt.Errorf("响应体包含重试标记: %q", marker)
+			if c.logLevel >= LogDebug {
+				log.Printf("[重试] %v", lastErr)
+			}
+			continue
+		}
+
+		if containsInt(opts.RetryOnStatus, response.Status) {
+			lastErr = fmt.Errorf("响应状态码 %d 需要重试", response.Status)
+			retryAfter = parseRetryAfter(response.Headers.Get("Retry-After"), time.Now(), c.maxRetryAfter)
+			if c.logLevel >= LogDebug {
+				log.Printf("[重试] %v", lastErr)
+			}
+			continue
 		}
 
+		c.storeResponse(key, response)
 		return response, nil
 	}
 
 	return nil, fmt.Errorf("请求失败，已重试 %d 次: %w", opts.RetryCount, lastErr)
 }
 
+// defaultMaxRetryAfter 按 Retry-After 等待的默认最长时间
+const defaultMaxRetryAfter = 60 * time.Second
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期），返回需要等待的时间，不超过 max
+// 头不存在或无法解析时返回 0，使用默认的递增延迟
+func parseRetryAfter(value string, now time.Time, max time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = t.Sub(now)
+	}
+	if d <= 0 {
+		return 0
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// contentLength 获取响应声明的长度，传输层未解析时回退到 Content-Length 头
+func contentLength(resp *http.Response) int64 {
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength
+	}
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		return n
+	}
+	return -1
+}
+
+// isAbsoluteURL 判断路径是否为 http(s):// 开头的完整 URL
+func isAbsoluteURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// unixURLPrefix Unix 套接字目标地址的前缀，格式为 unix:///var/run/docker.sock:/v1.41
+const unixURLPrefix = "unix://"
+
+// SetBaseURL 设置目标地址
+// unix:///套接字路径:/路径前缀 形式的地址会通过该 Unix 套接字发送请求
+func (c *HTTPClient) SetBaseURL(baseURL string) {
+	if strings.HasPrefix(baseURL, unixURLPrefix) {
+		socket, prefix, _ := strings.Cut(strings.TrimPrefix(baseURL, unixURLPrefix), ":")
+		c.unixSocket = socket
+		c.baseURL = "http://localhost" + prefix
+		return
+	}
+	// 从 Unix 套接字切换到网络目标时不再使用套接字，已建立的套接字连接也不再复用
+	if c.unixSocket != "" {
+		c.unixSocket = ""
+		c.transport.CloseIdleConnections()
+	}
+	// 未写协议时默认使用 http，如 127.0.0.1:8080、[::1]:8080
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	c.baseURL = baseURL
+}
+
+// joinURL 将规则路径拼接到目标地址上
+// 目标地址按 URL 解析后再组合，IPv6 地址（如 http://[::1]:8080）、端口和路径前缀都会保留，
+// 例如 https://host/api 与 /v1/x 拼接为 https://host/api/v1/x；目标地址中的查询参数放在规则查询参数之前。
+// 规则路径按原样拼接，不做转义，以免破坏 payload 中的特殊编码；
+// 规则路径本身是 http(s):// 开头的完整 URL 时直接使用，不再拼接目标地址
+func joinURL(baseURL, path string) (string, error) {
+	if isAbsoluteURL(path) {
+		return path, nil
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("无效的目标地址 %s: %w", baseURL, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("无效的目标地址 %s: 缺少主机", baseURL)
+	}
+
+	// 确保 path 以 / 开头
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if u.RawQuery != "" {
+		if p, query, ok := strings.Cut(path, "?"); ok {
+			path = p + "?" + u.RawQuery + "&" + query
+		} else {
+			path += "?" + u.RawQuery
+		}
+	}
+	// 主机部分重新转义，IPv6 zone（如 [fe80::1%25eth0]）解析后的 % 需要还原为 %25
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	return origin + strings.TrimSuffix(u.EscapedPath(), "/") + path, nil
+}
+
+// EnableCache 开启或关闭响应缓存，开启后相同的请求（方法、URL、请求头、Cookie、请求体均相同）
+// 直接返回之前的响应，不再发送；关闭时清空已有缓存。可并发使用
+func (c *HTTPClient) EnableCache(enabled bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cacheEnabled = enabled
+	c.cache = nil
+}
+
+// ClearCache 清空响应缓存
+func (c *HTTPClient) ClearCache() {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cache = nil
+}
+
+// cachedResponse 查找缓存的响应，key 为空表示该请求不使用缓存
+func (c *HTTPClient) cachedResponse(key string) (*Response, bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if !c.cacheEnabled || key == "" {
+		return nil, false
+	}
+	resp, ok := c.cache[key]
+	return resp, ok
+}
+
+func (c *HTTPClient) storeResponse(key string, resp *Response) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if !c.cacheEnabled || key == "" {
+		return
+	}
+	if c.cache == nil {
+		c.cache = make(map[string]*Response)
+	}
+	c.cache[key] = resp
+}
+
+// bodylessMethod 判断是否为通常不带请求体的方法
+func bodylessMethod(method string) bool {
+	return strings.EqualFold(method, http.MethodGet) || strings.EqualFold(method, http.MethodHead) || method == ""
+}
+
+// cacheKey 生成请求的缓存键，请求头按名称排序保证顺序稳定
+func cacheKey(url, cookie string, opts RequestOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\nHost: %s\nCookie: %s\n", opts.Method, url, opts.Host, cookie)
+	if opts.BasicAuth != nil {
+		fmt.Fprintf(&b, "Basic: %s:%s\n", opts.BasicAuth.User, opts.BasicAuth.Pass)
+	}
+	fmt.Fprintf(&b, "Bearer: %s\nChunked: %v\n", opts.BearerToken, opts.Chunked)
+	if opts.MaxRedirects != nil {
+		fmt.Fprintf(&b, "MaxRedirects: %d\n", *opts.MaxRedirects)
+	}
+
+	names := make([]string, 0, len(opts.Headers))
+	for k := range opts.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(&b, "%s: %s\n", strings.ToLower(k), opts.Headers[k])
+	}
+	for _, h := range opts.RawHeaders {
+		fmt.Fprintf(&b, "raw %s\n", h)
+	}
+	// 流式扫描的响应不保留响应体，不能给普通请求使用
+	for _, m := range opts.StreamMarkers {
+		fmt.Fprintf(&b, "stream %s\n", m)
+	}
+	b.WriteString("\n")
+	b.WriteString(opts.Body)
+	return b.String()
+}
+
+// ClearCookies 清空存储的 Cookie
+func (c *HTTPClient) ClearCookies() {
+	c.cookies = make(map[string]string)
+}
+
 // StoreCookie 存储提取的 Cookie
 func (c *HTTPClient) StoreCookie(cookieStr string) {
 	// 简单存储，实际可能需要解析多个 Cookie
@@ -205,3 +893,94 @@ func (c *HTTPClient) GetStoredCookie() string {
 	return c.cookies["extracted"]
 }
 
+
+// cookieRecord Cookie 持久化记录
+type cookieRecord struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+}
+
+// cookieAttributes Set-Cookie 中的属性名（小写），拆分存储的 Cookie 时不作为 Cookie
+var cookieAttributes = map[string]bool{
+	"path": true, "domain": true, "expires": true, "max-age": true,
+	"secure": true, "httponly": true, "samesite": true, "partitioned": true,
+}
+
+// parseStoredCookies 将存储的 Cookie 字符串（如 "session=abc; role=admin"，也可能是合并的 Set-Cookie 值）
+// 拆分为每个 Cookie 一条记录；Path、Domain 属性记录到前一个 Cookie 上，其余属性忽略
+func parseStoredCookies(s, domain string) []cookieRecord {
+	var records []cookieRecord
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if name == "" {
+			continue
+		}
+		if attr := strings.ToLower(name); cookieAttributes[attr] {
+			if n := len(records); n > 0 && ok && value != "" {
+				switch attr {
+				case "path":
+					records[n-1].Path = value
+				case "domain":
+					records[n-1].Domain = strings.TrimPrefix(value, ".")
+				}
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		records = append(records, cookieRecord{Name: name, Value: value, Domain: domain, Path: "/"})
+	}
+	return records
+}
+
+// SaveCookies 将存储的 Cookie 以 JSON 格式保存到文件，便于跨运行复用会话
+// 每个 Cookie 一条记录（名称、值、域名、路径），按存储时的顺序排列，相同的会话多次保存得到的文件内容相同
+func (c *HTTPClient) SaveCookies(path string) error {
+	domain := ""
+	if u, err := url.Parse(c.baseURL); err == nil {
+		domain = u.Hostname()
+	}
+
+	records := parseStoredCookies(c.GetStoredCookie(), domain)
+	if records == nil {
+		records = []cookieRecord{}
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 Cookie 失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("保存 Cookie 文件失败: %w", err)
+	}
+	return nil
+}
+
+// LoadCookies 从 SaveCookies 生成的 JSON 文件恢复 Cookie，按记录的顺序重新组成存储的 Cookie
+func (c *HTTPClient) LoadCookies(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取 Cookie 文件失败: %w", err)
+	}
+
+	var records []cookieRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("解析 Cookie 文件失败: %w", err)
+	}
+
+	parts := make([]string, 0, len(records))
+	for _, r := range records {
+		if r.Name != "" {
+			parts = append(parts, r.Name+"="+r.Value)
+		}
+	}
+	if len(parts) > 0 {
+		c.StoreCookie(strings.Join(parts, "; "))
+	}
+	return nil
+}
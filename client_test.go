@@ -0,0 +1,1205 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// textServer 启动返回固定响应体的测试服务器
+func textServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// unixServer 在临时目录的 Unix 套接字上启动 HTTP 服务器，返回套接字路径
+func unixServer(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("不支持 Unix 套接字: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return socket
+}
+
+func TestSetBaseURLLeavesUnixSocket(t *testing.T) {
+	socket := unixServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "unix")
+	})
+	tcpServer := textServer(t, "tcp")
+
+	client := NewHTTPClient("unix://" + socket)
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "unix" {
+		t.Fatalf("Unix 套接字响应体 = %q", resp.Body)
+	}
+
+	client.SetBaseURL(tcpServer.URL)
+	resp, err = client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "tcp" {
+		t.Errorf("切换目标后响应体 = %q，期望 tcp", resp.Body)
+	}
+}
+
+func TestCacheSeparatesStreamedResponses(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, "token=abc")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.EnableCache(true)
+	streamed, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/", StreamMarkers: []string{"token="}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !streamed.Streamed || !streamed.Markers["token="] {
+		t.Fatalf("流式扫描结果 = %+v", streamed)
+	}
+
+	plain, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Streamed || plain.Body != "token=abc" {
+		t.Errorf("普通请求的响应 Streamed=%v Body=%q，不应使用流式扫描的缓存", plain.Streamed, plain.Body)
+	}
+	if hits != 2 {
+		t.Errorf("请求次数 = %d，期望 2", hits)
+	}
+}
+
+func TestSaveAndLoadCookies(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Cookie")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.StoreCookie("session=abc; Path=/app; HttpOnly; role=admin")
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := client.SaveCookies(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []cookieRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	want := []cookieRecord{
+		{Name: "session", Value: "abc", Domain: "127.0.0.1", Path: "/app"},
+		{Name: "role", Value: "admin", Domain: "127.0.0.1", Path: "/"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("保存的 Cookie = %+v，期望 %+v", records, want)
+	}
+
+	restored := NewHTTPClient(server.URL)
+	if err := restored.LoadCookies(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.ExecuteRequest(RequestOptions{Method: "GET", Path: "/", UseCookie: "response.extracted_cookie"}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "session=abc; role=admin" {
+		t.Errorf("恢复后发送的 Cookie = %q", got)
+	}
+}
+
+// countingServer 启动统计新建连接数的测试服务器
+func countingServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func TestTransportReusesConnections(t *testing.T) {
+	server, conns := countingServer(t)
+	client := NewHTTPClient(server.URL)
+	for i := 0; i < 3; i++ {
+		if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(conns); got != 1 {
+		t.Errorf("复用连接时新建连接数 = %d，期望 1", got)
+	}
+
+	server, conns = countingServer(t)
+	client = NewHTTPClient(server.URL)
+	client.SetKeepAlive(false)
+	for i := 0; i < 3; i++ {
+		if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(conns); got != 3 {
+		t.Errorf("关闭 keep-alive 时新建连接数 = %d，期望 3", got)
+	}
+}
+
+func BenchmarkRepeatedRequests(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+	for i := 0; i < b.N; i++ {
+		if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRequestHookInjectsHeader(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Signature")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.SetRequestHook(func(req *http.Request) {
+		req.Header.Set("X-Signature", "signed-"+req.Method)
+	})
+	var seen *Response
+	client.SetResponseHook(func(resp *Response) {
+		seen = resp
+	})
+
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "signed-GET" {
+		t.Errorf("服务器收到的 X-Signature = %q", got)
+	}
+	if seen != resp {
+		t.Error("响应钩子应收到返回的响应")
+	}
+}
+
+// rawServer 启动 TCP 服务器，读取请求头后写出固定的原始响应，handle 收到原始请求（请求行和请求头）
+func rawServer(t *testing.T, response string, handle func(request string)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				var request strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					request.WriteString(line)
+					if line == "\r\n" {
+						break
+					}
+				}
+				if handle != nil {
+					handle(request.String())
+				}
+				io.WriteString(conn, response)
+			}(conn)
+		}
+	}()
+	return "http://" + listener.Addr().String()
+}
+
+func TestStatusTextFromCustomStatusLine(t *testing.T) {
+	url := rawServer(t, "HTTP/1.1 418 I'm a little teapot\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok", nil)
+	resp, err := NewHTTPClient(url).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusText != "I'm a little teapot" {
+		t.Fatalf("StatusText = %q", resp.StatusText)
+	}
+
+	e := NewExpressionEvaluator()
+	for _, expr := range []string{
+		"response.status_text.contains('teapot')",
+		"response.status_text == \"I'm a little teapot\"",
+		"response.status == 418",
+	} {
+		if !evalExpr(t, e, expr, resp) {
+			t.Errorf("%s 期望为 true", expr)
+		}
+	}
+}
+
+func TestChunkedTransferEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.Write([]byte("part1"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("part2"))
+			return
+		}
+		w.Write([]byte("plain"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	e := NewExpressionEvaluator()
+
+	chunked, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/chunked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunked.Body != "part1part2" {
+		t.Errorf("Body = %q", chunked.Body)
+	}
+	for _, expr := range []string{"response.is_chunked", "response.transfer_encoding == 'chunked'"} {
+		if !evalExpr(t, e, expr, chunked) {
+			t.Errorf("分块响应: %s 期望为 true", expr)
+		}
+	}
+
+	plain, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evalExpr(t, e, "response.is_chunked", plain) {
+		t.Error("普通响应的 is_chunked 期望为 false")
+	}
+}
+
+func TestHostOverride(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/", Host: "internal.example"}); err != nil {
+		t.Fatal(err)
+	}
+	if host != "internal.example" {
+		t.Errorf("服务器收到的 Host = %q，期望 internal.example", host)
+	}
+}
+
+func TestHeadRequestContentLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1234")
+		if r.Method != http.MethodHead {
+			w.Write(make([]byte, 1234))
+		}
+	}))
+	defer server.Close()
+
+	resp, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "HEAD", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "" || resp.ContentLength != 1234 {
+		t.Fatalf("Body = %q ContentLength = %d", resp.Body, resp.ContentLength)
+	}
+
+	e := NewExpressionEvaluator()
+	for _, expr := range []string{
+		"response.content_length == 1234",
+		"response.content_length > 1000",
+		"response.body.length == 0",
+	} {
+		if !evalExpr(t, e, expr, resp) {
+			t.Errorf("%s 期望为 true", expr)
+		}
+	}
+}
+
+func TestRequestContentLengthAndChunked(t *testing.T) {
+	type seen struct {
+		contentLength    int64
+		transferEncoding []string
+		body             string
+	}
+	var got seen
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = seen{r.ContentLength, r.TransferEncoding, string(body)}
+	}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "POST", Path: "/", Body: "a=1&b=2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.contentLength != 7 || len(got.transferEncoding) != 0 || got.body != "a=1&b=2" {
+		t.Errorf("普通请求体: %+v", got)
+	}
+
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "POST", Path: "/", Body: "a=1&b=2", Chunked: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got.contentLength != -1 || len(got.transferEncoding) != 1 || got.transferEncoding[0] != "chunked" || got.body != "a=1&b=2" {
+		t.Errorf("分块请求体: %+v", got)
+	}
+}
+
+func TestResolveFakeHostname(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := NewHTTPClient("http://victim.invalid:" + port)
+	client.Resolve("VICTIM.invalid", "127.0.0.1")
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	if host != "victim.invalid:"+port {
+		t.Errorf("服务器收到的 Host = %q，应保留原主机名", host)
+	}
+}
+
+func TestResolveKeepsTLSServerName(t *testing.T) {
+	var sni string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sni = r.TLS.ServerName
+	}))
+	server.StartTLS()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := NewHTTPClient("https://victim.example:" + port)
+	client.Resolve("victim.example", "127.0.0.1")
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	if sni != "victim.example" {
+		t.Errorf("SNI = %q，期望 victim.example", sni)
+	}
+}
+
+func TestRequestTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := NewHTTPClient("https://localhost:" + port)
+	first, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timings := first.Timings
+	if timings.DNS <= 0 || timings.Connect <= 0 || timings.TLSHandshake <= 0 {
+		t.Errorf("新连接的耗时未记录: %+v", timings)
+	}
+	if timings.FirstByte < 20*time.Millisecond {
+		t.Errorf("FirstByte = %v，期望不少于服务器处理时间 20ms", timings.FirstByte)
+	}
+
+	second, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timings := second.Timings; timings.DNS != 0 || timings.Connect != 0 || timings.TLSHandshake != 0 || timings.FirstByte <= 0 {
+		t.Errorf("复用连接的耗时 = %+v，期望只有 FirstByte", timings)
+	}
+}
+
+func TestDecodeGBKBody(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("<title>中文标题</title>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset="+strings.TrimPrefix(r.URL.Path, "/"))
+		fmt.Fprint(w, gbk)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/GBK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "<title>中文标题</title>" {
+		t.Errorf("Body = %q，期望转换为 UTF-8", resp.Body)
+	}
+	if !evalExpr(t, NewExpressionEvaluator(), "response.body.contains('中文')", resp) {
+		t.Error("UTF-8 字面量应能匹配 GBK 响应体")
+	}
+
+	raw, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/x-unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Body != gbk {
+		t.Errorf("未知字符集时应保留原始字节: %q", raw.Body)
+	}
+}
+
+func TestRetryOnBodyMarker(t *testing.T) {
+	t.Parallel() // 重试前等待 2 秒
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			fmt.Fprint(w, "请稍后再试")
+			return
+		}
+		fmt.Fprint(w, "real content")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	resp, err := client.ExecuteRequest(RequestOptions{
+		Method:              "GET",
+		Path:                "/",
+		RetryCount:          2,
+		RetryOnBodyContains: []string{"请稍后再试"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "real content" || atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("Body = %q, 请求次数 = %d，期望第二次拿到真实内容", resp.Body, hits)
+	}
+}
+
+func TestRetryOnBodyMarkerExhausted(t *testing.T) {
+	t.Parallel() // 重试前等待 2 秒
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, "请稍后再试")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	_, err := client.ExecuteRequest(RequestOptions{
+		Method:              "GET",
+		Path:                "/",
+		RetryCount:          1,
+		RetryOnBodyContains: []string{"请稍后再试"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "请稍后再试") {
+		t.Errorf("重试用尽后 err = %v，期望包含重试标记", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("请求次数 = %d，期望 1 次请求加 1 次重试", got)
+	}
+}
+
+func TestCacheServesIdenticalRequests(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.EnableCache(true)
+	opts := RequestOptions{Method: "POST", Path: "/api", Headers: map[string]string{"X-A": "1"}, Body: "q=1"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ExecuteRequest(opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := client.ExecuteRequest(opts); err != nil || resp.Body != "ok" {
+				t.Errorf("并发请求 = %v, %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("服务器收到 %d 次请求，期望相同请求只发送 1 次", got)
+	}
+
+	for _, changed := range []RequestOptions{
+		{Method: "POST", Path: "/api", Headers: map[string]string{"X-A": "2"}, Body: "q=1"},
+		{Method: "POST", Path: "/api", Headers: map[string]string{"X-A": "1"}, Body: "q=2"},
+		{Method: "PUT", Path: "/api", Headers: map[string]string{"X-A": "1"}, Body: "q=1"},
+	} {
+		if _, err := client.ExecuteRequest(changed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 4 {
+		t.Errorf("服务器收到 %d 次请求，不同的请求不应命中缓存", got)
+	}
+
+	client.EnableCache(false)
+	if _, err := client.ExecuteRequest(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 5 {
+		t.Errorf("关闭缓存后服务器收到 %d 次请求，期望 5", got)
+	}
+}
+
+func TestUnixSocketRequests(t *testing.T) {
+	var path, host string
+	socket := unixServer(t, func(w http.ResponseWriter, r *http.Request) {
+		path, host = r.URL.Path, r.Host
+		fmt.Fprint(w, `{"Version":"24.0.7"}`)
+	})
+
+	client := NewHTTPClient("unix://" + socket + ":/v1.41")
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/version"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1.41/version" || resp.Body != `{"Version":"24.0.7"}` {
+		t.Errorf("路径 = %q, 响应体 = %q", path, resp.Body)
+	}
+
+	client = NewHTTPClient("http://docker.local")
+	client.SetUnixSocket(socket)
+	if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/info"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/info" || host != "docker.local" {
+		t.Errorf("SetUnixSocket 后路径 = %q, Host = %q，期望保持不变", path, host)
+	}
+}
+
+func TestSetUnixSocketSwitchesBackToTCP(t *testing.T) {
+	socket := unixServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "unix")
+	})
+	tcpServer := textServer(t, "tcp")
+
+	client := NewHTTPClient(tcpServer.URL)
+	for _, c := range []struct {
+		socket string
+		want   string
+	}{
+		{socket, "unix"},
+		// 恢复为 TCP 后不能复用 Unix 套接字上空闲的长连接
+		{"", "tcp"},
+		{socket, "unix"},
+	} {
+		client.SetUnixSocket(c.socket)
+		resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Body != c.want {
+			t.Errorf("SetUnixSocket(%q) 后响应体 = %q，期望 %q", c.socket, resp.Body, c.want)
+		}
+	}
+}
+
+func TestDisableCompressionKeepsRawBytes(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("plain text"))
+	zw.Close()
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "plain text" {
+		t.Fatalf("默认应自动解压，Body = %q", resp.Body)
+	}
+
+	client.SetDisableCompression(true)
+	resp, err = client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != compressed.String() {
+		t.Errorf("关闭解压后 Body = %q，期望原始 gzip 字节", resp.Body)
+	}
+	if got := resp.Headers.Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q，期望保留 gzip", got)
+	}
+	if acceptEncoding != "" {
+		t.Errorf("关闭解压后不应自动发送 Accept-Encoding: %q", acceptEncoding)
+	}
+}
+
+// intPtr 返回指向 n 的指针，用于可选的数值选项
+func intPtr(n int) *int { return &n }
+
+func TestMaxRedirects(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		next := map[string]string{"/a": "/b", "/b": "/c", "/c": "/final"}[r.URL.Path]
+		if next != "" {
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "final")
+	}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+
+	tests := []struct {
+		max      *int
+		status   int
+		location string
+		paths    []string
+	}{
+		{intPtr(0), http.StatusFound, "/b", []string{"/a"}},
+		{intPtr(1), http.StatusFound, "/c", []string{"/a", "/b"}},
+		{intPtr(5), http.StatusOK, "", []string{"/a", "/b", "/c", "/final"}},
+		{nil, http.StatusOK, "", []string{"/a", "/b", "/c", "/final"}},
+	}
+	for _, tt := range tests {
+		paths = nil
+		resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/a", MaxRedirects: tt.max})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != tt.status || resp.Headers.Get("Location") != tt.location || !reflect.DeepEqual(paths, tt.paths) {
+			t.Errorf("max_redirects %v: 状态码 %d, Location %q, 请求路径 %v", tt.max, resp.Status, resp.Headers.Get("Location"), paths)
+		}
+	}
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: max-redirects
+rules:
+  r0:
+    method: GET
+    path: /a
+    max_redirects: 1
+    expression: response.status == 302 && response.headers.get('Location') == '/c'
+expression: r0()
+`), server.URL)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Errorf("max_redirects: 1 时 Execute() = %v, %v，期望停在第二跳的 302", matched, err)
+	}
+}
+
+func TestJoinURLIPv6(t *testing.T) {
+	for _, tt := range []struct{ base, path, want string }{
+		{"http://[::1]:8080", "/admin", "http://[::1]:8080/admin"},
+		{"http://[fe80::1%25eth0]:8080/", "admin", "http://[fe80::1%25eth0]:8080/admin"},
+		{"[::1]:8443", "/x", "http://[::1]:8443/x"},
+		{"127.0.0.1:8080", "/x?a=1", "http://127.0.0.1:8080/x?a=1"},
+		{"https://[2001:db8::1]/base/", "/v1", "https://[2001:db8::1]/base/v1"},
+	} {
+		client := NewHTTPClient(tt.base)
+		got, err := joinURL(client.baseURL, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("%s + %s = %q, %v，期望 %q", tt.base, tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := joinURL("http://", "/x"); err == nil {
+		t.Error("缺少主机的目标地址应返回错误")
+	}
+}
+
+func TestRequestOverIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("不支持 IPv6 回环地址: %v", err)
+	}
+	var path string
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			fmt.Fprint(w, "ipv6")
+		})},
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL + "/prefix")
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "ipv6" || path != "/prefix/status" {
+		t.Errorf("Body = %q, 路径 = %q", resp.Body, path)
+	}
+}
+
+func TestJoinURLKeepsBasePath(t *testing.T) {
+	for _, tt := range []struct{ base, path, want string }{
+		{"https://host/api", "/v1/x", "https://host/api/v1/x"},
+		{"https://host/api/", "/v1/x", "https://host/api/v1/x"},
+		{"https://host/api", "v1/x", "https://host/api/v1/x"},
+		{"https://host", "/v1/x", "https://host/v1/x"},
+		{"https://host/api?token=abc", "/v1/x", "https://host/api/v1/x?token=abc"},
+		{"https://host/api?token=abc", "/v1/x?id=1", "https://host/api/v1/x?token=abc&id=1"},
+		{"https://host/a%20b", "/%2e%2e/etc", "https://host/a%20b/%2e%2e/etc"},
+		{"https://host/api", "http://other:8080/raw", "http://other:8080/raw"},
+	} {
+		got, err := joinURL(tt.base, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("%s + %s = %q, %v，期望 %q", tt.base, tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestRequestWithBasePathPrefix(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL + "/api/?key=k")
+	for _, path := range []string{"/v1/users", "v1/items?page=2"} {
+		if _, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"/api/v1/users?key=k", "/api/v1/items?key=k&page=2"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("服务器收到 %v，期望 %v", requests, want)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"3":   3 * time.Second,
+		" 5 ": 5 * time.Second,
+		"120": time.Minute, // 超过上限时按上限等待
+		now.Add(10 * time.Second).Format(http.TimeFormat): 10 * time.Second,
+		now.Add(-time.Hour).Format(http.TimeFormat):       0,
+		"":     0,
+		"0":    0,
+		"-1":   0,
+		"soon": 0,
+	} {
+		if got := parseRetryAfter(value, now, time.Minute); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v，期望 %v", value, got, want)
+		}
+	}
+	if got := parseRetryAfter("120", now, 0); got != 2*time.Minute {
+		t.Errorf("max 为 0 时不限制，实际 %v", got)
+	}
+}
+
+func TestRetryOnStatusHonoursRetryAfter(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	// Retry-After 要求的 30 秒被截断为 50ms，比默认的 2 秒延迟短，说明按 Retry-After 等待
+	client.SetMaxRetryAfter(50 * time.Millisecond)
+	start := time.Now()
+	resp, err := client.ExecuteRequest(RequestOptions{
+		Method:        "GET",
+		Path:          "/",
+		RetryCount:    2,
+		RetryOnStatus: []int{503},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != 200 || atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("Status = %d, 请求次数 = %d，期望第二次成功", resp.Status, hits)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("重试等待了 %v，期望约 50ms", elapsed)
+	}
+}
+
+func TestRetryOnStatusExhausted(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.SetMaxRetryAfter(10 * time.Millisecond)
+	_, err := client.ExecuteRequest(RequestOptions{
+		Method:        "GET",
+		Path:          "/",
+		RetryCount:    2,
+		RetryOnStatus: []int{429},
+	})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("重试用尽期望返回状态码错误，实际 %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("请求次数 = %d，期望 3", n)
+	}
+}
+
+func TestResponseJSON(t *testing.T) {
+	server := textServer(t, `{"version": "1.2.3", "plugins": ["a", "b"]}`)
+	resp, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := resp.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["version"] != "1.2.3" || len(data["plugins"].([]interface{})) != 2 {
+		t.Errorf("JSON() = %v", data)
+	}
+
+	// 解析结果已缓存，修改响应体不影响后续调用和表达式中的 JSON 取值
+	resp.Body = "not json"
+	if again, err := resp.JSON(); err != nil || again["version"] != "1.2.3" {
+		t.Errorf("再次调用 JSON() = %v, %v，期望使用缓存", again, err)
+	}
+	if !evalExpr(t, NewExpressionEvaluator(), "response.body.json('$.version') == '1.2.3'", resp) {
+		t.Error("表达式中的 JSON 取值期望使用缓存的解析结果")
+	}
+}
+
+func TestResponseJSONErrors(t *testing.T) {
+	for _, body := range []string{"<html></html>", `["array"]`, `"string"`, ""} {
+		resp := &Response{Status: 200, Headers: make(http.Header), Body: body}
+		if data, err := resp.JSON(); err == nil {
+			t.Errorf("JSON(%q) = %v，期望返回错误", body, data)
+		}
+	}
+}
+
+func TestBlockInsecureRedirect(t *testing.T) {
+	plain := textServer(t, "plain http")
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/downgrade":
+			http.Redirect(w, r, plain.URL+"/landing", http.StatusFound)
+		case "/same-scheme":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			fmt.Fprint(w, "https final")
+		}
+	}))
+	defer secure.Close()
+
+	// 默认跟随降级跳转
+	resp, err := NewHTTPClient(secure.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/downgrade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "plain http" || resp.InsecureRedirect != "" {
+		t.Errorf("默认: Body = %q, InsecureRedirect = %q", resp.Body, resp.InsecureRedirect)
+	}
+
+	client := NewHTTPClient(secure.URL)
+	client.SetBlockInsecureRedirect(true)
+	resp, err = client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/downgrade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != http.StatusFound || resp.InsecureRedirect != plain.URL+"/landing" {
+		t.Errorf("阻止降级: Status = %d, InsecureRedirect = %q", resp.Status, resp.InsecureRedirect)
+	}
+	e := NewExpressionEvaluator()
+	if !evalExpr(t, e, "response.insecure_redirect", resp) {
+		t.Error("response.insecure_redirect 期望为 true")
+	}
+
+	// https 之间的跳转照常跟随
+	resp, err = client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/same-scheme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "https final" || resp.InsecureRedirect != "" {
+		t.Errorf("同协议跳转: Body = %q, InsecureRedirect = %q", resp.Body, resp.InsecureRedirect)
+	}
+	if evalExpr(t, e, "response.insecure_redirect", resp) {
+		t.Error("未降级时 response.insecure_redirect 期望为 false")
+	}
+}
+
+func TestSignerSeesFinalRequest(t *testing.T) {
+	const secret = "s3cr3t"
+	sign := func(method, path, hook string, body []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		fmt.Fprintf(mac, "%s\n%s\n%s\n", method, path, hook)
+		mac.Write(body)
+		return "HMAC " + hex.EncodeToString(mac.Sum(nil))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != sign(r.Method, r.URL.Path, r.Header.Get("X-Hook"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "signed body: %s", body)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.SetRequestHook(func(req *http.Request) { req.Header.Set("X-Hook", "set-before-signing") })
+	client.SetSigner(func(req *http.Request) error {
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		req.Header.Set("Authorization", sign(req.Method, req.URL.Path, req.Header.Get("X-Hook"), body))
+		return nil
+	})
+
+	resp, err := client.ExecuteRequest(RequestOptions{Method: "POST", Path: "/api", Body: `{"action":"list"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 签名时读取过的请求体仍完整发送
+	if resp.Status != 200 || resp.Body != `signed body: {"action":"list"}` {
+		t.Errorf("Status = %d, Body = %q，期望签名校验通过", resp.Status, resp.Body)
+	}
+}
+
+func TestSignerErrorFailsWithoutRetry(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	client.SetSigner(func(req *http.Request) error { return errors.New("missing credentials") })
+	_, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/", RetryCount: 2})
+	if err == nil || !strings.Contains(err.Error(), "missing credentials") {
+		t.Errorf("签名失败期望返回错误，实际 %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("签名失败时发送了 %d 个请求", n)
+	}
+}
+
+func TestSerializedHeadersDeterministic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"X-Zeta", "X-Alpha", "X-Mid", "Server", "Cache-Control", "X-Beta"} {
+			w.Header().Set(name, strings.ToLower(name))
+		}
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Set("Date", "Mon, 15 Jan 2024 08:00:00 GMT")
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	var serialized []string
+	for i := 0; i < 2; i++ {
+		resp, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, _ := responsePart(resp, "header")
+		raw, _ := responsePart(resp, "raw")
+		serialized = append(serialized, header+"\n--\n"+raw)
+	}
+	if serialized[0] != serialized[1] {
+		t.Errorf("两次序列化的响应头不同:\n%s\n\n%s", serialized[0], serialized[1])
+	}
+	// 按名称排序，同名字段保持原有顺序
+	if !strings.Contains(serialized[0], "Server: server\nSet-Cookie: b=2\nSet-Cookie: a=1\nX-Alpha: x-alpha") {
+		t.Errorf("响应头未按名称排序:\n%s", serialized[0])
+	}
+}
+
+func TestSaveCookiesDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	var files [][]byte
+	for i := 0; i < 2; i++ {
+		client := NewHTTPClient("http://example.com")
+		client.StoreCookie("session=abc; role=admin; Path=/app; theme=dark")
+		path := filepath.Join(dir, fmt.Sprintf("cookies%d.json", i))
+		if err := client.SaveCookies(path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, data)
+	}
+	if !bytes.Equal(files[0], files[1]) {
+		t.Errorf("两次保存的 Cookie 文件不同:\n%s\n\n%s", files[0], files[1])
+	}
+}
+
+func TestGetRequestWithBody(t *testing.T) {
+	type received struct {
+		method, body, contentType string
+		contentLength             int64
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = received{r.Method, string(body), r.Header.Get("Content-Type"), r.ContentLength}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	query := `{"query":{"match_all":{}}}`
+	for _, c := range []struct {
+		opts RequestOptions
+		want received
+	}{
+		{RequestOptions{Method: "GET", Path: "/_search", Body: query}, received{"GET", query, "application/json", int64(len(query))}},
+		{RequestOptions{Method: "GET", Path: "/_search", Body: "q=test"}, received{"GET", "q=test", "", 6}},
+		{RequestOptions{Method: "GET", Path: "/_search", Body: query, Headers: map[string]string{"content-type": "application/x-ndjson"}},
+			received{"GET", query, "application/x-ndjson", int64(len(query))}},
+	} {
+		if _, err := client.ExecuteRequest(c.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("%+v: 服务器收到 %+v，期望 %+v", c.opts, got, c.want)
+		}
+	}
+
+	// 调试日志中提示 GET 请求携带请求体
+	client.SetLogLevel(LogDebug)
+	output := captureLog(t, func() {
+		client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/_search", Body: query})
+	})
+	if !strings.Contains(output, "GET 请求携带请求体") {
+		t.Errorf("日志中缺少警告:\n%s", output)
+	}
+}
+
+func TestTLSVersionsAndCipherSuites(t *testing.T) {
+	// 服务器只接受 TLS 1.2 和一个加密套件
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	server.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // 握手失败是预期的
+	server.StartTLS()
+	defer server.Close()
+
+	e := NewExpressionEvaluator()
+	for _, c := range []struct {
+		name     string
+		min, max uint16
+		suites   []uint16
+		ok       bool
+	}{
+		{"默认配置", 0, 0, nil, true},
+		{"只允许 TLS 1.2", tls.VersionTLS12, tls.VersionTLS12, nil, true},
+		{"只允许 TLS 1.3", tls.VersionTLS13, tls.VersionTLS13, nil, false},
+		{"相同的加密套件", 0, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, true},
+		{"不同的加密套件", 0, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, false},
+	} {
+		client := NewHTTPClient(server.URL)
+		client.SetTLSVersions(c.min, c.max)
+		client.SetCipherSuites(c.suites)
+		resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+		if !c.ok {
+			if err == nil {
+				t.Errorf("%s: 期望握手失败", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		for _, expr := range []string{
+			"response.tls.version == 'TLS 1.2'",
+			"response.tls.cipher == 'TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256'",
+		} {
+			if !evalExpr(t, e, expr, resp) {
+				t.Errorf("%s: %s 期望为 true", c.name, expr)
+			}
+		}
+	}
+
+	// 非 HTTPS 响应时为空
+	plain, err := NewHTTPClient(textServer(t, "ok").URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !evalExpr(t, e, "response.tls.version == ''", plain) {
+		t.Error("HTTP 响应的 response.tls.version 期望为空")
+	}
+}
@@ -1,8 +1,17 @@
 package sdk
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,24 +24,61 @@ type POCConfig struct {
 	Category  string            `yaml:"category"`
 	CVEID     string            `yaml:"cve_id"`
 	Level     string            `yaml:"level"`
-	Source    string            `yaml:"source"`
-	S1        string            `yaml:"s1"`
+	Source    string            `yaml:"source"` // 参考链接（漏洞公告、分析文章等），须为 http(s) 地址
+	S1        string            `yaml:"s1"`     // 一句话描述，随结果一同输出
+	Include   string            `yaml:"include"` // 公共规则默认值文件，相对于 POC 文件
 	Rules     map[string]*Rule  `yaml:"rules"`
 	Expression string           `yaml:"expression"`
+	Headers   map[string]string `yaml:"headers"` // 所有规则共用的请求头，规则中同名（不区分大小写）的请求头优先
+	WAFSignatures []string      `yaml:"waf_signatures"` // WAF 拦截页特征，任一响应命中时结果标记为 BlockedByWAF
+	Threshold int               `yaml:"threshold"` // 打分模式的阈值，成功规则的权重之和达到该值即为匹配，0 表示不使用
+
+	ruleOrder []string // 规则在 YAML 中的声明顺序
+	baseDir   string   // POC 文件所在目录，从内存加载时为当前工作目录
 }
 
 // Rule 单个规则定义
 type Rule struct {
+	Name            string            `yaml:"name"`        // 规则的可读名称，用于日志和 RuleResult
+	Description     string            `yaml:"description"` // 规则检查内容的说明，用于日志和 RuleResult
 	Method          string            `yaml:"method"`
 	Path            string            `yaml:"path"`
 	Timeout         int               `yaml:"timeout"`
 	RetryCount      int               `yaml:"retry_count"`
+	RetryOnStatus   []int             `yaml:"retry_on_status"` // 响应状态码为其中之一时重试（计入 retry_count），有 Retry-After 头时按其等待
+	RetryOnBodyContains []string      `yaml:"retry_on_body_contains"` // 响应体包含任一标记时重试
+	Repeat          int               `yaml:"repeat"`       // 重复发送请求的次数，表达式针对最后一次的响应
+	RepeatDelay     int               `yaml:"repeat_delay"` // 重复请求之间的间隔（毫秒）
 	Headers         map[string]string `yaml:"headers"`
+	Extractors      map[string]string `yaml:"extractors"` // 变量名到取值表达式，请求后依次求值并存入变量上下文
+	JSONBody        map[string]interface{} `yaml:"json_body"` // 编码为 JSON 作为请求体，未设置 Content-Type 时使用 application/json
+	RawHeaders      []string          `yaml:"raw_headers"` // "Name: value" 形式的请求头，按原样的大小写和顺序发送
 	Body            []string          `yaml:"body"`
 	ExtractCookie   string            `yaml:"extract_cookie"`
 	UseCookie       string            `yaml:"use_cookie"`
 	CookieExpression string           `yaml:"cookie_expression"`
+	StreamMarkers   []string          `yaml:"stream_markers"` // 流式扫描响应体中的标记，不保留响应体（用于超大响应）
+	NoCookies       bool              `yaml:"no_cookies"` // 该请求不发送 Cookie，用于对比未登录时的响应
+	Condition       string            `yaml:"condition"` // 前置条件，不满足时跳过该规则
+	Payloads        []string          `yaml:"payloads"`  // 逐个替换 {{payload}} 重复执行该规则
+	PayloadGenerator string           `yaml:"payload_generator"` // 已注册的载荷生成器，如 "range:1-100"，在 payloads 之后使用
+	Host            string            `yaml:"host"` // 覆盖请求的 Host 头，连接目标仍为 baseURL
+	Chunked         bool              `yaml:"chunked"` // 强制使用分块编码发送请求体（用于请求走私测试）
+	MaxRedirects    *int              `yaml:"max_redirects"` // 最多跟随的重定向次数，0 表示不跟随
+	BasicAuth       *BasicAuth        `yaml:"basic_auth"`
+	BearerToken     string            `yaml:"bearer_token"` // 支持 {{name}} 变量引用
+	MatchFirstBytes int               `yaml:"match_first_bytes"` // 表达式和匹配器只检查响应体的前 N 个字节，0 表示不限制
+	Weight          int               `yaml:"weight"` // 打分模式下规则成功时计入的分数
+	ExpectStatus    []int             `yaml:"expect_status"` // 预期状态码，响应状态码在其中才可能匹配，与 expression 同时满足
 	Expression      string            `yaml:"expression"`
+	Matchers        []Matcher         `yaml:"matchers"`           // 匹配器列表，可与 expression 同时使用
+	MatchersCondition string          `yaml:"matchers-condition"` // 匹配器组合方式：and / or（默认）
+}
+
+// BasicAuth HTTP Basic 认证信息
+type BasicAuth struct {
+	User string `yaml:"user"`
+	Pass string `yaml:"pass"`
 }
 
 // LoadConfig 从文件加载 POC 配置
@@ -42,14 +88,372 @@ func LoadConfig(filePath string) (*POCConfig, error) {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
+	return parseConfig(data, filePath, false)
+}
+
+// LoadConfigStrict 从文件加载 POC 配置，并拒绝未知字段（如拼写错误的字段名）
+func LoadConfigStrict(filePath string) (*POCConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	return parseConfig(data, filePath, true)
+}
+
+// LoadConfigReader 从 io.Reader 加载 POC 配置（如 embed.FS 中的文件、HTTP 响应）
+// include 等相对路径相对于当前工作目录解析
+func LoadConfigReader(r io.Reader) (*POCConfig, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	return LoadConfigBytes(data)
+}
+
+// LoadConfigBytes 从内存中的 YAML 数据加载 POC 配置
+// include 等相对路径相对于当前工作目录解析
+func LoadConfigBytes(data []byte) (*POCConfig, error) {
+	return parseConfig(data, "", false)
+}
+
+// parseConfig 解析并校验 POC 配置
+// filePath 用于错误信息和解析相对路径（为空时相对于当前工作目录），strict 为 true 时拒绝未知字段
+func parseConfig(data []byte, filePath string, strict bool) (*POCConfig, error) {
+	baseDir := "."
+	if filePath != "" {
+		baseDir = filepath.Dir(filePath)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, newConfigError(filePath, data, err)
+	}
+
 	config := &POCConfig{}
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("解析 YAML 配置失败: %w", err)
+	if err := root.Decode(config); err != nil {
+		return nil, newConfigError(filePath, data, err)
+	}
+	config.ruleOrder = ruleOrderFromNode(&root)
+	config.baseDir = baseDir
+
+	if strict {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&POCConfig{}); err != nil && err != io.EOF {
+			return nil, newConfigError(filePath, data, err)
+		}
+	}
+
+	if config.Include != "" {
+		includePath := config.Include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(baseDir, includePath)
+		}
+		defaults, err := loadRuleDefaults(includePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range config.Rules {
+			rule.applyDefaults(defaults)
+		}
+	}
+
+	// 读取 @file: 引用的请求体
+	for name, rule := range config.Rules {
+		if rule == nil {
+			continue
+		}
+		for i, body := range rule.Body {
+			if !strings.HasPrefix(body, fileRefPrefix) {
+				continue
+			}
+			content, err := readFileRef(baseDir, strings.TrimPrefix(body, fileRefPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的请求体: %w", name, err)
+			}
+			rule.Body[i] = content
+		}
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// fileRefPrefix 文件引用前缀，如 @file:payload.xml
+const fileRefPrefix = "@file:"
+
+// readFileRef 读取相对于 baseDir 的文件，拒绝绝对路径和跳出 baseDir 的路径（如 ../secret）
+func readFileRef(baseDir, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || filepath.IsAbs(name) {
+		return "", fmt.Errorf("无效的文件引用: %s", name)
+	}
+
+	path := filepath.Join(baseDir, name)
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("文件引用超出允许的目录: %s", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取引用文件失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// ConfigError POC 配置解析错误，包含文件路径和出错的行号
+type ConfigError struct {
+	Path string // 配置文件路径，从内存加载时为空
+	Line int    // 出错的行号，未知时为 0
+	Msg  string
+	Err  error // yaml.v3 返回的原始错误
+}
+
+func (e *ConfigError) Error() string {
+	loc := e.Path
+	if loc == "" {
+		loc = "<config>"
+	}
+	if e.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, e.Line)
+	}
+	return fmt.Sprintf("解析 YAML 配置失败 %s: %s", loc, e.Msg)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// yamlLineRegex 匹配 yaml.v3 错误信息中的行号
+var yamlLineRegex = regexp.MustCompile(`line (\d+): (.*)`)
+
+// unknownFieldRegex 匹配 KnownFields 模式下的未知字段错误
+var unknownFieldRegex = regexp.MustCompile(`field (\S+) not found in type (\S+)`)
+
+// newConfigError 将 yaml.v3 的错误转换为带路径和行号的 ConfigError
+func newConfigError(filePath string, data []byte, err error) error {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+		if len(typeErr.Errors) > 1 {
+			msg += fmt.Sprintf("（共 %d 处错误）", len(typeErr.Errors))
+		}
+	}
+	msg = strings.TrimPrefix(msg, "yaml: ")
+
+	configErr := &ConfigError{Path: filePath, Msg: msg, Err: err}
+	if matches := yamlLineRegex.FindStringSubmatch(msg); matches != nil {
+		configErr.Line, _ = strconv.Atoi(matches[1])
+		configErr.Msg = matches[2]
+	}
+
+	if matches := unknownFieldRegex.FindStringSubmatch(configErr.Msg); matches != nil {
+		configErr.Msg = fmt.Sprintf("未知字段 %s（%s 中没有该字段，请检查拼写）", matches[1], matches[2])
+	}
+
+	// 常见错误：使用 Tab 缩进
+	lines := strings.Split(string(data), "\n")
+	if configErr.Line > 0 && configErr.Line <= len(lines) && strings.Contains(lines[configErr.Line-1], "\t") {
+		configErr.Msg += "（该行包含 Tab，YAML 只能使用空格缩进）"
+	}
+
+	return configErr
+}
+
+// validate 校验配置的基本完整性
+func (c *POCConfig) validate() error {
+	// 按执行顺序校验，多个规则有误时报告的总是同一个
+	for _, name := range c.RuleNames() {
+		rule := c.Rules[name]
+		if rule == nil {
+			return fmt.Errorf("规则 %s 的定义为空", name)
+		}
+		if rule.JSONBody != nil && len(rule.Body) > 0 {
+			return fmt.Errorf("规则 %s 不能同时设置 body 和 json_body", name)
+		}
+		for _, h := range rule.RawHeaders {
+			if n, _, ok := strings.Cut(h, ":"); !ok || strings.TrimSpace(n) == "" {
+				return fmt.Errorf("规则 %s 的原始请求头格式应为 \"Name: value\": %q", name, h)
+			}
+		}
+	}
+	if c.Source != "" {
+		u, err := url.Parse(c.Source)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("source 不是有效的 http(s) 地址: %s", c.Source)
+		}
+	}
+	return nil
+}
+
+// ruleOrderFromNode 从 YAML 节点中读取 rules 下规则的声明顺序
+func ruleOrderFromNode(root *yaml.Node) []string {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "rules" {
+			continue
+		}
+		rules := doc.Content[i+1]
+		if rules.Kind != yaml.MappingNode {
+			return nil
+		}
+		var order []string
+		for j := 0; j+1 < len(rules.Content); j += 2 {
+			order = append(order, rules.Content[j].Value)
+		}
+		return order
+	}
+	return nil
+}
+
+// RuleNames 按声明顺序返回规则名称，未记录顺序的规则按名称排序追加在末尾
+func (c *POCConfig) RuleNames() []string {
+	names := make([]string, 0, len(c.Rules))
+	seen := make(map[string]bool, len(c.Rules))
+	for _, name := range c.ruleOrder {
+		if _, ok := c.Rules[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range c.Rules {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// mergeHeaders 返回合并后的请求头，overrides 中的请求头覆盖 base 中同名（不区分大小写）的请求头
+// base 为空时直接返回 overrides
+func mergeHeaders(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 {
+		return overrides
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		for bk := range merged {
+			if strings.EqualFold(bk, k) {
+				delete(merged, bk)
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// loadRuleDefaults 加载 include 引用的公共规则默认值
+func loadRuleDefaults(filePath string) (*Rule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取 include 文件失败: %w", err)
+	}
+
+	defaults := &Rule{}
+	if err := yaml.Unmarshal(data, defaults); err != nil {
+		return nil, newConfigError(filePath, data, err)
+	}
+	return defaults, nil
+}
+
+// applyDefaults 合并公共默认值，规则自身的设置优先
+// 请求头按名称（不区分大小写）逐项合并；method、timeout、retry_count、body 仅在规则未设置时使用默认值，其余字段不合并
+func (r *Rule) applyDefaults(defaults *Rule) {
+	if r == nil || defaults == nil {
+		return
+	}
+
+	r.Headers = mergeHeaders(defaults.Headers, r.Headers)
+
+	if r.Method == "" {
+		r.Method = defaults.Method
+	}
+	if r.Timeout == 0 {
+		r.Timeout = defaults.Timeout
+	}
+	if r.RetryCount == 0 {
+		r.RetryCount = defaults.RetryCount
+	}
+	if len(r.Body) == 0 {
+		r.Body = defaults.Body
+	}
+}
+
+// label 返回日志中显示的规则名称：设置了 name 时为 "r0（name）"，否则为规则名
+func (r *Rule) label(ruleName string) string {
+	if r == nil || r.Name == "" {
+		return ruleName
+	}
+	return fmt.Sprintf("%s（%s）", ruleName, r.Name)
+}
+
+// templateRefs 返回规则的请求（path、body、json_body、headers、raw_headers、host、bearer_token、use_cookie）中引用的变量名
+func (r *Rule) templateRefs() map[string]bool {
+	refs := make(map[string]bool)
+	collect := func(s string) string {
+		for _, m := range templateRegex.FindAllStringSubmatch(s, -1) {
+			refs[m[1]] = true
+		}
+		return s
+	}
+	collect(r.Path)
+	collect(r.Host)
+	collect(r.BearerToken)
+	collect(r.UseCookie)
+	for _, line := range r.Body {
+		collect(line)
+	}
+	for k, v := range r.Headers {
+		collect(k)
+		collect(v)
+	}
+	for _, h := range r.RawHeaders {
+		collect(h)
+	}
+	if r.JSONBody != nil {
+		mapStrings(r.JSONBody, collect)
+	}
+	return refs
+}
+
+// namedGroupRegex 匹配正则中的命名捕获组，如 (?P<token>\w+)、(?<token>\w+)
+var namedGroupRegex = regexp.MustCompile(`\(\?P?<(\w+)>`)
+
+// definesAny 判断规则是否会设置 refs 中的变量：extractors 的变量名或 extract_cookie 的命名捕获组
+func (r *Rule) definesAny(refs map[string]bool) bool {
+	for name := range r.Extractors {
+		if refs[name] {
+			return true
+		}
+	}
+	for _, m := range namedGroupRegex.FindAllStringSubmatch(r.ExtractCookie, -1) {
+		if refs[m[1]] {
+			return true
+		}
+	}
+	return false
+}
+
 // GetTimeout 获取超时时间（秒转 Duration）
 // 最小超时时间为 60 秒，避免 TLS 握手超时（HTTPS 需要更长时间）
 func (r *Rule) GetTimeout() time.Duration {
@@ -71,6 +475,59 @@ func (r *Rule) GetRetryCount() int {
 	return r.RetryCount
 }
 
+// withPayload 返回将 {{payload}} 替换为指定值后的规则副本
+func (r *Rule) withPayload(payload string) *Rule {
+	replace := func(s string) string {
+		return strings.ReplaceAll(s, "{{payload}}", payload)
+	}
+
+	rule := *r
+	rule.Path = replace(r.Path)
+	if len(r.Body) > 0 {
+		rule.Body = make([]string, len(r.Body))
+		for i, b := range r.Body {
+			rule.Body[i] = replace(b)
+		}
+	}
+	if len(r.Headers) > 0 {
+		rule.Headers = make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			rule.Headers[k] = replace(v)
+		}
+	}
+	if r.JSONBody != nil {
+		rule.JSONBody = mapStrings(r.JSONBody, replace).(map[string]interface{})
+	}
+	if len(r.RawHeaders) > 0 {
+		rule.RawHeaders = make([]string, len(r.RawHeaders))
+		for i, h := range r.RawHeaders {
+			rule.RawHeaders[i] = replace(h)
+		}
+	}
+	return &rule
+}
+
+// mapStrings 返回将 v 中的字符串（含嵌套对象和数组中的）经 fn 转换后的副本
+func mapStrings(v interface{}, fn func(string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return fn(val)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = mapStrings(item, fn)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = mapStrings(item, fn)
+		}
+		return list
+	}
+	return v
+}
+
 // GetBody 获取请求体字符串
 func (r *Rule) GetBody() string {
 	if len(r.Body) == 0 {
@@ -0,0 +1,232 @@
+package sdk
+
+import (
+	"embed"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile 在目录中写入测试文件并返回路径
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIncludeDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "common.yaml", `
+headers:
+  User-Agent: "gopoc"
+  Accept: "*/*"
+timeout: 7
+`)
+	path := writeFile(t, dir, "poc.yaml", `
+name: include
+include: common.yaml
+rules:
+  r0:
+    method: GET
+    path: /
+    headers:
+      accept: "text/html"
+  r1:
+    method: GET
+    path: /
+    timeout: 3
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r0 := config.Rules["r0"]
+	if r0.Headers["User-Agent"] != "gopoc" {
+		t.Errorf("r0 未继承 User-Agent: %v", r0.Headers)
+	}
+	if _, ok := r0.Headers["Accept"]; ok || r0.Headers["accept"] != "text/html" {
+		t.Errorf("r0 的 accept 应覆盖 include 中的 Accept: %v", r0.Headers)
+	}
+	if r0.Timeout != 7 {
+		t.Errorf("r0.Timeout = %d，期望继承 7", r0.Timeout)
+	}
+
+	r1 := config.Rules["r1"]
+	if r1.Headers["Accept"] != "*/*" || r1.Timeout != 3 {
+		t.Errorf("r1 = headers %v timeout %d", r1.Headers, r1.Timeout)
+	}
+}
+
+//go:embed testdata/embedded.yaml
+var embeddedPOCs embed.FS
+
+func TestLoadConfigBytes(t *testing.T) {
+	config, err := LoadConfigBytes([]byte(`
+name: in-memory
+rules:
+  r0:
+    method: GET
+    path: /
+expression: r0()
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "in-memory" || config.Rules["r0"] == nil {
+		t.Errorf("配置 = %+v", config)
+	}
+
+	// 与 LoadConfig 一致，没有规则的配置也能加载
+	if config, err := LoadConfigBytes([]byte("name: empty\n")); err != nil || config.Name != "empty" {
+		t.Errorf("没有规则的配置 = %+v, %v，期望正常加载", config, err)
+	}
+	if _, err := LoadConfig(writeFile(t, t.TempDir(), "empty.yaml", "name: empty\n")); err != nil {
+		t.Errorf("LoadConfig 加载没有规则的配置失败: %v", err)
+	}
+}
+
+func TestLoadConfigReaderFromEmbedFS(t *testing.T) {
+	f, err := embeddedPOCs.Open("testdata/embedded.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	config, err := LoadConfigReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "embedded" || config.Level != "high" || config.Rules["r0"].Path != "/" {
+		t.Errorf("配置 = %+v", config)
+	}
+}
+
+func TestStrictUnknownField(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "typo.yaml", `name: typo
+rules:
+  r0:
+    method: GET
+    pth: /
+`)
+
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("非严格模式应忽略未知字段: %v", err)
+	}
+
+	_, err := LoadConfigStrict(path)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("err = %v，期望 *ConfigError", err)
+	}
+	if configErr.Path != path || configErr.Line != 5 || !strings.Contains(configErr.Msg, "pth") {
+		t.Errorf("ConfigError = %+v", configErr)
+	}
+	if !strings.Contains(err.Error(), path) {
+		t.Errorf("错误信息应包含文件路径: %v", err)
+	}
+}
+
+func TestBadIndentReportsLine(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "indent.yaml", "name: indent\nrules:\n  r0:\n\tmethod: GET\n")
+
+	_, err := LoadConfig(path)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("err = %v，期望 *ConfigError", err)
+	}
+	if configErr.Line != 4 || !strings.Contains(configErr.Msg, "Tab") {
+		t.Errorf("ConfigError = %+v", configErr)
+	}
+}
+
+func TestBodyFromFileReference(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "payload.xml", `<?xml version="1.0"?><foo>&xxe;</foo>`)
+	path := writeFile(t, dir, "poc.yaml", `
+name: file-body
+rules:
+  r0:
+    method: POST
+    path: /
+    body:
+      - "@file:payload.xml"
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := config.Rules["r0"].Body; len(body) != 1 || body[0] != `<?xml version="1.0"?><foo>&xxe;</foo>` {
+		t.Errorf("Body = %q", body)
+	}
+}
+
+func TestBodyFileReferenceRejectsEscape(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "secret.txt", "secret")
+	dir := filepath.Join(root, "pocs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"../secret.txt", "sub/../../secret.txt", filepath.Join(root, "secret.txt")} {
+		path := writeFile(t, dir, "poc.yaml", `
+name: escape
+rules:
+  r0:
+    method: POST
+    path: /
+    body:
+      - "@file:`+ref+`"
+`)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("@file:%s 应被拒绝", ref)
+		}
+	}
+}
+
+func TestJSONBodyConflictsWithBody(t *testing.T) {
+	_, err := LoadConfigBytes([]byte(`
+name: both-bodies
+rules:
+  r0:
+    method: POST
+    path: /
+    body: ["a=1"]
+    json_body:
+      a: 1
+    expression: response.status == 200
+expression: r0()
+`))
+	if err == nil || !strings.Contains(err.Error(), "json_body") {
+		t.Errorf("同时设置 body 和 json_body 期望报错，实际 %v", err)
+	}
+}
+
+func TestValidateReportsRulesInOrder(t *testing.T) {
+	data := []byte(`
+name: two-bad-rules
+rules:
+  zz:
+    method: GET
+    path: /
+    raw_headers: ["bad"]
+  aa:
+    method: GET
+    path: /
+    raw_headers: ["also bad"]
+`)
+	for i := 0; i < 10; i++ {
+		_, err := LoadConfigBytes(data)
+		if err == nil || !strings.Contains(err.Error(), "规则 zz") {
+			t.Fatalf("期望总是报告声明顺序中的第一个错误规则 zz，实际 %v", err)
+		}
+	}
+}
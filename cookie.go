@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
 )
@@ -29,20 +30,12 @@ func (ce *CookieExtractor) ExtractCookie(expr string, response *Response) (strin
 		re := regexp.MustCompile(`response\.headers\.get\(['"]([^'"]+)['"]\)`)
 		matches := re.FindStringSubmatch(expr)
 		if len(matches) != 2 {
-			return "", fmt.Errorf("无法解析 headers.get 表达式: %s", expr)
+			return "", fmt.Errorf("%w: 无法解析 headers.get 表达式: %s", ErrUnsupportedExpression, expr)
 		}
 
-		headerName := matches[1]
-		headerNameLower := strings.ToLower(headerName)
-
-		// 查找 Set-Cookie 头
-		for k, v := range response.Headers {
-			if strings.ToLower(k) == headerNameLower {
-				if len(v) > 0 {
-					// 如果有多个 Set-Cookie，合并它们
-					return strings.Join(v, "; "), nil
-				}
-			}
+		// 查找 Set-Cookie 头，如果有多个 Set-Cookie，合并它们
+		if values := response.Headers.Values(matches[1]); len(values) > 0 {
+			return strings.Join(values, "; "), nil
 		}
 
 		// 也检查 Cookies 字段（http.Cookie）
@@ -57,12 +50,27 @@ func (ce *CookieExtractor) ExtractCookie(expr string, response *Response) (strin
 		return "", nil
 	}
 
+	// 处理 response.body.extract_all('pattern')，返回所有匹配项
+	if strings.Contains(expr, "response.body.extract_all") {
+		re := regexp.MustCompile(`response\.body\.extract_all\(['"]([^'"]+)['"]\)`)
+		matches := re.FindStringSubmatch(expr)
+		if len(matches) != 2 {
+			return "", fmt.Errorf("%w: 无法解析 body.extract_all 表达式: %s", ErrUnsupportedExpression, expr)
+		}
+
+		values, err := extractAll(matches[1], response.Body)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(values, "; "), nil
+	}
+
 	// 处理 response.body.extract('pattern')
 	if strings.Contains(expr, "response.body.extract") {
 		re := regexp.MustCompile(`response\.body\.extract\(['"]([^'"]+)['"]\)`)
 		matches := re.FindStringSubmatch(expr)
 		if len(matches) != 2 {
-			return "", fmt.Errorf("无法解析 body.extract 表达式: %s", expr)
+			return "", fmt.Errorf("%w: 无法解析 body.extract 表达式: %s", ErrUnsupportedExpression, expr)
 		}
 
 		pattern := matches[1]
@@ -71,7 +79,7 @@ func (ce *CookieExtractor) ExtractCookie(expr string, response *Response) (strin
 
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
-			return "", fmt.Errorf("无效的正则表达式: %w", err)
+			return "", fmt.Errorf("%w: %w", ErrBadRegex, err)
 		}
 
 		match := regex.FindStringSubmatch(response.Body)
@@ -88,7 +96,56 @@ func (ce *CookieExtractor) ExtractCookie(expr string, response *Response) (strin
 		return "", fmt.Errorf("extracted_cookie 需要从执行上下文获取")
 	}
 
-	return "", fmt.Errorf("不支持的 Cookie 提取表达式: %s", expr)
+	return "", fmt.Errorf("%w: 不支持的 Cookie 提取表达式 %s", ErrUnsupportedExpression, expr)
+}
+
+// ExtractNamedGroups 从 response.body.extract('pattern') 的首个匹配中提取所有命名捕获组
+// 返回以组名为键的映射，非 body.extract 表达式或没有命名组时返回空映射
+func (ce *CookieExtractor) ExtractNamedGroups(expr string, response *Response) (map[string]string, error) {
+	groups := make(map[string]string)
+	if response == nil || !strings.Contains(expr, "response.body.extract(") {
+		return groups, nil
+	}
+
+	re := regexp.MustCompile(`response\.body\.extract\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return nil, fmt.Errorf("%w: 无法解析 body.extract 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	regex, err := regexp.Compile(convertRustRegex(matches[1]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadRegex, err)
+	}
+
+	match := regex.FindStringSubmatch(response.Body)
+	if match == nil {
+		return groups, nil
+	}
+	for i, name := range regex.SubexpNames() {
+		if name != "" && i < len(match) {
+			groups[name] = match[i]
+		}
+	}
+	return groups, nil
+}
+
+// extractAll 返回正则在文本中的所有匹配，有捕获组时取第一个捕获组
+func extractAll(pattern, text string) ([]string, error) {
+	regex, err := regexp.Compile(convertRustRegex(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadRegex, err)
+	}
+
+	var values []string
+	for _, match := range regex.FindAllStringSubmatch(text, -1) {
+		if len(match) > 1 {
+			values = append(values, match[1])
+		} else {
+			values = append(values, match[0])
+		}
+	}
+	return values, nil
 }
 
 // convertRustRegex 将 Rust 正则语法转换为 Go 正则语法
@@ -105,7 +162,8 @@ func convertRustRegex(pattern string) string {
 	return pattern
 }
 
-// ValidateCookie 验证 Cookie 表达式
+// ValidateCookie 单独验证 Cookie 表达式，表达式中的 response.xxx 作用于一个空的 200 响应
+// 引擎执行规则时使用自身的评估器，可以访问规则的实际响应和变量
 func (ce *CookieExtractor) ValidateCookie(expr string, cookie string) (bool, error) {
 	if expr == "" {
 		return true, nil
@@ -113,7 +171,7 @@ func (ce *CookieExtractor) ValidateCookie(expr string, cookie string) (bool, err
 
 	evaluator := NewExpressionEvaluator()
 	// 创建一个虚拟响应，因为 cookie_expression 主要操作 cookie
-	dummyResponse := &Response{Status: 200, Body: "", Headers: make(map[string][]string)}
+	dummyResponse := &Response{Status: 200, Body: "", Headers: make(http.Header)}
 	return evaluator.Evaluate(expr, dummyResponse, cookie)
 }
 
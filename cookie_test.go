@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractCookieExtractAll(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header), Body: "id=1,id=2,id=3"}
+	got, err := NewCookieExtractor().ExtractCookie("response.body.extract_all('id=(\\d+)')", response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1; 2; 3" {
+		t.Errorf("extract_all = %q，期望 %q", got, "1; 2; 3")
+	}
+}
+
+func TestExtractNamedGroups(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header), Body: "user=alice token=abc123"}
+	groups, err := NewCookieExtractor().ExtractNamedGroups("response.body.extract('user=(?P<user>\\w+) token=(?P<token>\\w+)')", response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups["user"] != "alice" || groups["token"] != "abc123" || len(groups) != 2 {
+		t.Errorf("命名捕获组 = %v", groups)
+	}
+}
+
+func TestValidateCookie(t *testing.T) {
+	ce := NewCookieExtractor()
+	for _, c := range []struct {
+		expr   string
+		cookie string
+		want   bool
+	}{
+		{"cookie.contains('session')", "session=abc", true},
+		{"cookie.contains('session')", "other=abc", false},
+		{"", "", true},
+		// 单独验证时 response.xxx 作用于空的 200 响应
+		{"response.status == 200 && response.body == ''", "", true},
+	} {
+		got, err := ce.ValidateCookie(c.expr, c.cookie)
+		if err != nil {
+			t.Fatalf("%q: %v", c.expr, err)
+		}
+		if got != c.want {
+			t.Errorf("ValidateCookie(%q, %q) = %v，期望 %v", c.expr, c.cookie, got, c.want)
+		}
+	}
+}
+
+func TestCookieExpressionSeesRuleResponse(t *testing.T) {
+	for body, want := range map[string]bool{
+		"session=abc123 logout": true,
+		"session=abc123 login":  false,
+	} {
+		server := textServer(t, body)
+		engine := NewEngine(mustLoadConfig(t, `
+name: cookie-expression
+rules:
+  r0:
+    method: GET
+    path: /
+    extract_cookie: response.body.extract('(session=\w+)')
+    cookie_expression: cookie.contains('session=abc123') && response.body.contains('logout')
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != want {
+			t.Errorf("响应 %q: Execute() = %v，期望 %v", body, matched, want)
+		}
+	}
+}
+
+func TestCookieExpressionUsesEngineContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, "tok=abc123")
+			return
+		}
+		fmt.Fprint(w, "s=abc123 logout")
+	}))
+	defer server.Close()
+
+	for cookieExpr, want := range map[string]bool{
+		"cookie.get('s') == {{tok}}":                              true,
+		"cookie.get('s') == {{tok}} && r0.response.status == 200": true,
+		"same(cookie.get('s'), {{tok}})":                          true,
+		"cookie.get('s') == 'other'":                              false,
+	} {
+		engine := NewEngine(mustLoadConfig(t, `
+name: cookie-context
+rules:
+  r0:
+    method: GET
+    path: /token
+    extractors:
+      tok: response.body.extract('tok=(\w+)')
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('(s=\w+)')
+    cookie_expression: `+cookieExpr+`
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+		engine.RegisterFunc("same", func(args []string) (interface{}, error) {
+			return len(args) == 2 && args[0] == args[1], nil
+		})
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatalf("%s: %v", cookieExpr, err)
+		}
+		if matched != want {
+			t.Errorf("%s: Execute() = %v，期望 %v", cookieExpr, matched, want)
+		}
+	}
+}
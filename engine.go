@@ -1,9 +1,15 @@
 package sdk
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Engine POC 执行引擎
@@ -13,7 +19,19 @@ type Engine struct {
 	evaluator    *ExpressionEvaluator
 	cookieExtractor *CookieExtractor
 	ruleResults  map[string]bool // 存储规则执行结果
-	verbose      bool
+	ruleSkipped  map[string]bool // 因前置条件不满足而跳过的规则
+	running      map[string]bool // 惰性模式下正在执行的规则，防止循环依赖
+	lazy         bool            // 惰性模式：按主表达式的需要执行规则
+	deadline     time.Duration   // 整个 POC 执行的总时限，0 表示不限制
+	ctx          context.Context // 当前执行的 context
+	logLevel     LogLevel
+	wafSignatures []string // 通过 SetWAFSignatures 设置的 WAF 拦截页特征，与配置中的 waf_signatures 一起使用
+	blockedByWAF bool      // 本次执行中是否有响应命中 WAF 拦截页特征
+	templateValues []string // 本次执行中生成的模板值（{{rand:8}} 等）的变量名，下次执行前清除
+	baseURL      string
+	result       Result // 最近一次执行的结果
+	onRuleStart    func(ruleName string)  // 规则开始执行时调用
+	onRuleComplete func(result RuleResult) // 规则执行结束（含跳过、出错）时调用
 }
 
 // NewEngine 创建执行引擎
@@ -25,25 +43,152 @@ func NewEngine(config *POCConfig, baseURL string) *Engine {
 		evaluator:    NewExpressionEvaluator(),
 		cookieExtractor: NewCookieExtractor(),
 		ruleResults:  make(map[string]bool),
-		verbose:      false,
+		ruleSkipped:  make(map[string]bool),
+		running:      make(map[string]bool),
+		ctx:          context.Background(),
+		baseURL:      baseURL,
 	}
 }
 
-// SetVerbose 设置详细输出模式
+// NewEngineFromConfig 创建未绑定目标的执行引擎，之后通过 SetTarget 指定目标
+// 同一份解析好的配置可以依次用于多个目标
+func NewEngineFromConfig(config *POCConfig) *Engine {
+	return NewEngine(config, "")
+}
+
+// SetTarget 切换目标地址，并清空上一次执行留下的状态
+func (e *Engine) SetTarget(baseURL string) {
+	e.Reset()
+	e.baseURL = baseURL
+	e.httpClient.SetBaseURL(baseURL)
+}
+
+// Reset 清空规则结果、变量上下文和 Cookie，避免复用引擎时状态在目标之间串扰
+func (e *Engine) Reset() {
+	e.ruleResults = make(map[string]bool)
+	e.ruleSkipped = make(map[string]bool)
+	e.running = make(map[string]bool)
+	e.evaluator.Reset()
+	e.httpClient.ClearCookies()
+	e.httpClient.ClearCache()
+	e.blockedByWAF = false
+	e.templateValues = nil
+	e.result = Result{}
+}
+
+// RegisterFunc 注册自定义表达式函数，规则表达式和主表达式中都可以使用
+func (e *Engine) RegisterFunc(name string, fn func(args []string) (interface{}, error)) {
+	e.evaluator.RegisterFunc(name, fn)
+}
+
+// SetWAFSignatures 设置 WAF 拦截页的特征，与配置中的 waf_signatures 一起使用
+// 任一响应的响应体或响应头包含其中的特征时，结果标记为 BlockedByWAF，用于区分被拦截和确实未命中
+func (e *Engine) SetWAFSignatures(signatures []string) {
+	e.wafSignatures = signatures
+}
+
+// SetOOBProvider 设置带外交互服务，用于检测无回显漏洞：
+// 请求中的 {{oob}} 替换为本次执行的回连域名，表达式 oob.received() 查询该域名是否收到过请求
+func (e *Engine) SetOOBProvider(provider OOBProvider) {
+	e.evaluator.SetOOBProvider(provider)
+}
+
+// SetMaxRequests 设置每次执行的请求数上限（含重试和载荷迭代），超过后中止执行并返回 ErrMaxRequestsExceeded，
+// 已执行规则的结果仍记录在 Result 中。n <= 0 表示不限制
+func (e *Engine) SetMaxRequests(n int) {
+	e.httpClient.SetMaxRequests(n)
+}
+
+// OnRuleStart 设置规则开始执行时的回调，可用于显示进度
+func (e *Engine) OnRuleStart(fn func(ruleName string)) {
+	e.onRuleStart = fn
+}
+
+// OnRuleComplete 设置规则执行结束时的回调，因前置条件跳过或出错的规则同样会回调
+func (e *Engine) OnRuleComplete(fn func(result RuleResult)) {
+	e.onRuleComplete = fn
+}
+
+// SetVerbose 设置详细输出模式，true 相当于 SetLogLevel(LogDebug)
 func (e *Engine) SetVerbose(verbose bool) {
-	e.verbose = verbose
-	e.httpClient.SetVerbose(verbose)
+	e.SetLogLevel(verboseLevel(verbose))
+}
+
+// SetLogLevel 设置日志级别，同时作用于引擎使用的 HTTP 客户端
+func (e *Engine) SetLogLevel(level LogLevel) {
+	e.logLevel = level
+	e.httpClient.SetLogLevel(level)
+}
+
+// HTTPClient 获取引擎使用的 HTTP 客户端，用于设置钩子等高级选项
+func (e *Engine) HTTPClient() *HTTPClient {
+	return e.httpClient
+}
+
+// SetLazy 设置惰性执行模式
+// 开启后先解析主表达式，只在求值需要时才执行对应规则，&& / || 短路时不会发送多余的请求
+func (e *Engine) SetLazy(lazy bool) {
+	e.lazy = lazy
+}
+
+// SetDeadline 设置整个 POC 执行的总时限，超时后剩余规则不再执行
+func (e *Engine) SetDeadline(d time.Duration) {
+	e.deadline = d
 }
 
 // Execute 执行整个 POC
 func (e *Engine) Execute() (bool, error) {
-	// 先执行所有规则
-	for ruleName, rule := range e.config.Rules {
-		success, err := e.executeRule(ruleName, rule)
-		if err != nil {
-			return false, fmt.Errorf("执行规则 %s 失败: %w", ruleName, err)
+	return e.ExecuteContext(context.Background())
+}
+
+// ExecuteContext 在给定 context 下执行整个 POC
+// context 取消或超出总时限时中止剩余规则，已执行规则的结果仍记录在 Result 中
+func (e *Engine) ExecuteContext(ctx context.Context) (bool, error) {
+	if e.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.deadline)
+		defer cancel()
+	}
+	e.ctx = ctx
+	defer func() { e.ctx = context.Background() }()
+	e.httpClient.ResetRequestCount()
+	e.evaluator.SetBaseDir(e.config.baseDir)
+	e.blockedByWAF = false
+	// 规则结果和生成的模板值只属于本次执行，Cookie 和其余变量仍由 Reset 清空
+	e.clearTemplateValues()
+	e.evaluator.resetOOB()
+	e.ruleResults = make(map[string]bool)
+	e.ruleSkipped = make(map[string]bool)
+	e.running = make(map[string]bool)
+
+	start := time.Now()
+	matched, err := e.execute()
+	e.recordResult(start, matched, err)
+	return matched, err
+}
+
+// execute 执行所有规则并评估主表达式
+func (e *Engine) execute() (bool, error) {
+	// 惰性模式下由主表达式按需触发规则执行（打分模式需要执行全部规则）
+	if e.lazy && e.config.Expression != "" && e.config.Threshold <= 0 {
+		return e.evaluateMainExpression(e.config.Expression)
+	}
+
+	// 按声明顺序执行所有规则
+	for _, ruleName := range e.config.RuleNames() {
+		if _, err := e.runRule(ruleName); err != nil {
+			return false, err
+		}
+	}
+
+	// 打分模式：成功规则的权重之和达到阈值即为匹配，配置了主表达式时还需同时满足
+	if e.config.Threshold > 0 {
+		if e.score() < e.config.Threshold {
+			return false, nil
+		}
+		if e.config.Expression == "" {
+			return true, nil
 		}
-		e.ruleResults[ruleName] = success
 	}
 
 	// 评估主表达式
@@ -51,9 +196,9 @@ func (e *Engine) Execute() (bool, error) {
 		return e.evaluateMainExpression(e.config.Expression)
 	}
 
-	// 如果没有主表达式，检查所有规则是否都成功
-	for _, success := range e.ruleResults {
-		if !success {
+	// 如果没有主表达式，检查所有已执行的规则是否都成功
+	for ruleName, success := range e.ruleResults {
+		if !success && !e.ruleSkipped[ruleName] {
 			return false, nil
 		}
 	}
@@ -61,123 +206,629 @@ func (e *Engine) Execute() (bool, error) {
 	return true, nil
 }
 
-// executeRule 执行单个规则
+// runRule 执行规则并触发开始、结束回调
+func (e *Engine) runRule(ruleName string) (bool, error) {
+	if e.onRuleStart != nil {
+		e.onRuleStart(ruleName)
+	}
+	if rule := e.config.Rules[ruleName]; rule != nil && e.logLevel >= LogDebug {
+		if rule.Description != "" {
+			log.Printf("[规则] 执行 %s: %s", rule.label(ruleName), rule.Description)
+		} else {
+			log.Printf("[规则] 执行 %s", rule.label(ruleName))
+		}
+	}
+	success, err := e.checkAndExecuteRule(ruleName)
+	if e.onRuleComplete != nil {
+		e.onRuleComplete(e.ruleResult(ruleName, success, err))
+	}
+	return success, err
+}
+
+// checkAndExecuteRule 检查前置条件并执行规则，记录执行结果
+func (e *Engine) checkAndExecuteRule(ruleName string) (bool, error) {
+	rule := e.config.Rules[ruleName]
+
+	if err := e.ctx.Err(); err != nil {
+		return false, fmt.Errorf("执行规则 %s 前已超出总时限: %w", ruleName, err)
+	}
+
+	// 前置条件不满足时跳过该规则，记为未执行
+	if rule.Condition != "" {
+		ok, err := e.evaluateMainExpression(rule.Condition)
+		if err != nil {
+			return false, fmt.Errorf("评估规则 %s 的前置条件失败: %w", ruleName, err)
+		}
+		if !ok {
+			if e.logLevel >= LogInfo {
+				log.Printf("[跳过] 规则 %s 的前置条件不满足: %s", rule.label(ruleName), rule.Condition)
+			}
+			e.ruleResults[ruleName] = false
+			e.ruleSkipped[ruleName] = true
+			return false, nil
+		}
+	}
+
+	delete(e.ruleSkipped, ruleName)
+	success, err := e.executeRule(ruleName, rule)
+	if err != nil {
+		return false, fmt.Errorf("执行规则 %s 失败: %w", ruleName, err)
+	}
+	e.ruleResults[ruleName] = success
+	return success, nil
+}
+
+// score 计算成功规则的权重之和
+func (e *Engine) score() int {
+	total := 0
+	for name, success := range e.ruleResults {
+		if rule := e.config.Rules[name]; success && rule != nil {
+			total += rule.Weight
+		}
+	}
+	return total
+}
+
+// ExecuteRule 单独执行一条规则，便于调试 POC 时查看其响应和表达式结果
+// 前置条件、变量上下文和 Cookie 沿用引擎当前的状态
+func (e *Engine) ExecuteRule(ruleName string) (RuleResult, error) {
+	if _, ok := e.config.Rules[ruleName]; !ok {
+		return RuleResult{}, fmt.Errorf("规则 %s 不存在", ruleName)
+	}
+
+	e.evaluator.SetBaseDir(e.config.baseDir)
+	success, err := e.runRule(ruleName)
+	return e.ruleResult(ruleName, success, err), err
+}
+
+// ruleResult 汇总规则的执行结果
+func (e *Engine) ruleResult(ruleName string, success bool, err error) RuleResult {
+	result := RuleResult{
+		Name:    ruleName,
+		Matched: success,
+		Skipped: e.ruleSkipped[ruleName],
+	}
+	if rule := e.config.Rules[ruleName]; rule != nil {
+		result.DisplayName = rule.Name
+		result.Description = rule.Description
+	}
+	if response, ok := e.evaluator.GetRuleResponse(ruleName); ok {
+		result.Response = response
+	}
+	if payload, ok := e.evaluator.GetVariable(ruleName + ".payload"); ok && success {
+		result.Payload = fmt.Sprintf("%v", payload)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// ruleValue 获取规则结果，惰性模式下规则尚未执行时立即执行
+func (e *Engine) ruleValue(ruleName string) (bool, error) {
+	if result, ok := e.ruleResults[ruleName]; ok {
+		return result, nil
+	}
+	if !e.lazy || e.running[ruleName] {
+		return false, nil
+	}
+	rule, ok := e.config.Rules[ruleName]
+	if !ok {
+		return false, nil
+	}
+
+	e.running[ruleName] = true
+	defer delete(e.running, ruleName)
+
+	// 先按声明顺序执行排在前面的依赖规则：使用提取的 Cookie 时执行 Cookie 提取规则，
+	// 请求中引用变量时执行提取这些变量的规则，避免把未解析的 {{name}} 发送出去
+	refs := rule.templateRefs()
+	for _, name := range e.config.RuleNames() {
+		if name == ruleName {
+			break
+		}
+		dep := e.config.Rules[name]
+		if !(rule.UseCookie != "" && dep.ExtractCookie != "") && !dep.definesAny(refs) {
+			continue
+		}
+		if _, err := e.ruleValue(name); err != nil {
+			return false, err
+		}
+	}
+
+	return e.runRule(ruleName)
+}
+
+// executeRule 执行单个规则，配置了 payloads 时逐个替换执行，任一次匹配即视为成功
 func (e *Engine) executeRule(ruleName string, rule *Rule) (bool, error) {
+	if len(rule.Payloads) == 0 && rule.PayloadGenerator == "" {
+		return e.executeOnce(ruleName, rule)
+	}
+
+	for _, payload := range rule.Payloads {
+		success, err := e.executePayload(ruleName, rule, payload)
+		if err != nil || success {
+			return success, err
+		}
+	}
+
+	if rule.PayloadGenerator != "" {
+		gen, err := newPayloadGenerator(rule.PayloadGenerator)
+		if err != nil {
+			return false, err
+		}
+		for payload, ok := gen.Next(); ok; payload, ok = gen.Next() {
+			success, err := e.executePayload(ruleName, rule, payload)
+			if err != nil || success {
+				return success, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// executePayload 使用单个载荷执行规则，匹配时记录到 <规则名>.payload
+func (e *Engine) executePayload(ruleName string, rule *Rule, payload string) (bool, error) {
+	if e.logLevel >= LogDebug {
+		log.Printf("[载荷] 规则 %s 使用 payload: %s", ruleName, payload)
+	}
+	success, err := e.executeOnce(ruleName, rule.withPayload(payload))
+	if err != nil {
+		return false, err
+	}
+	if success {
+		e.evaluator.SetVariable(ruleName+".payload", payload)
+	}
+	return success, nil
+}
+
+// executeOnce 发送一次请求并评估规则
+func (e *Engine) executeOnce(ruleName string, rule *Rule) (bool, error) {
+	// 解析 use_cookie 中的变量引用（如 {{session}}），未定义的变量保持原样
+	useCookie := e.resolveTemplate(rule.UseCookie)
+
 	// 准备请求选项
 	opts := RequestOptions{
 		Method:     rule.Method,
-		Path:       rule.Path,
-		Headers:    rule.Headers,
-		Body:       rule.GetBody(),
-		UseCookie:  rule.UseCookie,
+		Path:       e.resolveTemplate(rule.Path),
+		Headers:    e.resolveHeaders(mergeHeaders(e.config.Headers, rule.Headers)),
+		Body:       e.resolveTemplate(rule.GetBody()),
+		UseCookie:  useCookie,
+		Host:       e.resolveTemplate(rule.Host),
+		Chunked:    rule.Chunked,
 		Timeout:    rule.GetTimeout(),
 		RetryCount: rule.GetRetryCount(),
+		RetryOnBodyContains: rule.RetryOnBodyContains,
+		RetryOnStatus: rule.RetryOnStatus,
+		MaxRedirects: rule.MaxRedirects,
+		NoCookies:    rule.NoCookies,
+		StreamMarkers: rule.StreamMarkers,
+		BasicAuth:   rule.BasicAuth,
+		BearerToken: e.resolveTemplate(rule.BearerToken),
+		RawHeaders:  e.resolveRawHeaders(rule.RawHeaders),
+	}
+	if rule.JSONBody != nil {
+		body, err := e.jsonBody(rule.JSONBody)
+		if err != nil {
+			return false, err
+		}
+		opts.Body = body
+		opts.Headers = withDefaultHeader(opts.Headers, "Content-Type", "application/json")
 	}
 
 	// 执行 HTTP 请求
-	response, err := e.httpClient.ExecuteRequest(opts)
+	response, err := e.sendRequest(ruleName, rule, opts)
 	if err != nil {
 		return false, fmt.Errorf("HTTP 请求失败: %w", err)
 	}
+	e.evaluator.SetRuleResponse(ruleName, response)
+	e.checkWAF(ruleName, response)
 
 	// 提取 Cookie
 	if rule.ExtractCookie != "" {
 		cookie, err := e.cookieExtractor.ExtractCookie(rule.ExtractCookie, response)
 		if err == nil && cookie != "" {
 			e.httpClient.StoreCookie(cookie)
+			e.evaluator.SetVariable("extracted_cookie", cookie)
+		}
+
+		// 命名捕获组（如 (?P<token>\w+)）按组名存入变量上下文
+		groups, err := e.cookieExtractor.ExtractNamedGroups(rule.ExtractCookie, response)
+		if err == nil {
+			for name, value := range groups {
+				e.evaluator.SetVariable(name, value)
+			}
 		}
 	}
 
+	// 按 extractors 提取变量
+	e.runExtractors(ruleName, rule.Extractors, response)
+
 	// 验证 Cookie 表达式
 	if rule.CookieExpression != "" {
 		cookieToValidate := e.httpClient.GetStoredCookie()
-		if rule.UseCookie != "" {
+		if useCookie != "" {
 			// 如果规则指定了 use_cookie，使用它
-			if rule.UseCookie != "response.extracted_cookie" {
-				cookieToValidate = rule.UseCookie
+			if useCookie != "response.extracted_cookie" {
+				cookieToValidate = useCookie
 			}
 		}
-		valid, err := e.cookieExtractor.ValidateCookie(rule.CookieExpression, cookieToValidate)
+		// 与规则表达式使用同一个评估器，可以引用变量、自定义函数和 rN.response
+		valid, err := e.evaluator.Evaluate(rule.CookieExpression, response, cookieToValidate)
 		if err != nil {
 			return false, fmt.Errorf("Cookie 验证失败: %w", err)
 		}
 		if !valid {
-			return false, fmt.Errorf("Cookie 验证不通过")
+			if e.logLevel >= LogInfo {
+				log.Printf("[规则] %s Cookie 验证不通过", rule.label(ruleName))
+			}
+			return false, nil
+		}
+	}
+
+	// 检查预期状态码
+	if len(rule.ExpectStatus) > 0 && !containsInt(rule.ExpectStatus, response.Status) {
+		if e.logLevel >= LogInfo {
+			log.Printf("[规则] %s 状态码 %d 不在预期范围 %v 内", rule.label(ruleName), response.Status, rule.ExpectStatus)
 		}
+		return false, nil
 	}
 
+	// 设置了 match_first_bytes 时表达式和匹配器只检查响应体开头的字节，记录的规则响应仍为完整响应
+	matchResponse := response.bodyPrefix(rule.MatchFirstBytes)
+
 	// 评估规则表达式
 	if rule.Expression != "" {
 		cookieStr := e.httpClient.GetStoredCookie()
-		valid, err := e.evaluator.Evaluate(rule.Expression, response, cookieStr)
+		valid, err := e.evaluator.Evaluate(rule.Expression, matchResponse, cookieStr)
 		if err != nil {
 			return false, fmt.Errorf("表达式评估失败: %w", err)
 		}
 		if !valid {
-			return false, fmt.Errorf("规则表达式不满足: %s", rule.Expression)
+			if e.logLevel >= LogInfo {
+				log.Printf("[规则] %s 表达式不满足: %s", rule.label(ruleName), rule.Expression)
+			}
+			return false, nil
+		}
+	}
+
+	// 评估匹配器
+	if len(rule.Matchers) > 0 {
+		matched, err := evaluateMatchers(rule.Matchers, rule.MatchersCondition, matchResponse)
+		if err != nil {
+			return false, fmt.Errorf("匹配器评估失败: %w", err)
+		}
+		if !matched {
+			if e.logLevel >= LogInfo {
+				log.Printf("[规则] %s 匹配器不满足", rule.label(ruleName))
+			}
+			return false, nil
 		}
 	}
 
 	return true, nil
 }
 
+// templateRegex 匹配 {{name}} 形式的变量引用
+var templateRegex = regexp.MustCompile(`\{\{\s*([\w.:-]+)\s*\}\}`)
+
+// sendRequest 发送规则的请求，设置了 repeat 时按间隔重复发送，返回最后一次的响应
+// 每次的状态码按顺序记录到变量 <规则名>.statuses（逗号分隔）
+func (e *Engine) sendRequest(ruleName string, rule *Rule, opts RequestOptions) (*Response, error) {
+	if rule.Repeat <= 1 {
+		return e.httpClient.ExecuteRequestContext(e.ctx, opts)
+	}
+
+	// 重复请求必须真正发出，不使用响应缓存
+	opts.NoCache = true
+	delay := time.Duration(rule.RepeatDelay) * time.Millisecond
+	statuses := make([]string, 0, rule.Repeat)
+	var response *Response
+	for i := 0; i < rule.Repeat; i++ {
+		if i > 0 && delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-e.ctx.Done():
+				return nil, fmt.Errorf("重复请求已取消: %w", e.ctx.Err())
+			}
+		}
+
+		resp, err := e.httpClient.ExecuteRequestContext(e.ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 次重复请求失败: %w", i+1, err)
+		}
+		response = resp
+		statuses = append(statuses, strconv.Itoa(resp.Status))
+		if e.logLevel >= LogDebug {
+			log.Printf("[重复] 规则 %s 第 %d/%d 次，状态码: %d", ruleName, i+1, rule.Repeat, resp.Status)
+		}
+	}
+	e.evaluator.SetVariable(ruleName+".statuses", strings.Join(statuses, ","))
+	return response, nil
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTemplate 将字符串中的 {{name}} 替换为上下文变量，未定义的变量保持原样
+func (e *Engine) resolveTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return templateRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := templateRegex.FindStringSubmatch(match)[1]
+		if val, ok := e.evaluator.GetVariable(name); ok {
+			return fmt.Sprintf("%v", val)
+		}
+		// {{rand:8}}、{{uuid}}、{{timestamp}} 首次使用时生成并存入变量上下文，本次执行之后的规则得到相同的值
+		if val, ok := generateTemplateValue(name); ok {
+			e.evaluator.SetVariable(name, val)
+			e.templateValues = append(e.templateValues, name)
+			return val
+		}
+		// {{oob}} 同样只生成一次，oob.received() 查询的就是这个域名
+		if name == "oob" {
+			if val, ok := e.evaluator.newOOBDomain(); ok {
+				return val
+			}
+		}
+		return match
+	})
+}
+
+// clearTemplateValues 删除上一次执行生成的模板值，下次执行使用时重新生成
+func (e *Engine) clearTemplateValues() {
+	for _, name := range e.templateValues {
+		e.evaluator.deleteVariable(name)
+	}
+	e.templateValues = nil
+}
+
+// runExtractors 按变量名顺序对响应求值各提取表达式，结果存入变量上下文
+// 与 extract_cookie 一致，提取失败时不影响规则结果，只在日志中说明
+func (e *Engine) runExtractors(ruleName string, extractors map[string]string, response *Response) {
+	if len(extractors) == 0 {
+		return
+	}
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cookie := e.httpClient.GetStoredCookie()
+	for _, name := range names {
+		val, err := e.evaluator.Extract(extractors[name], response, cookie)
+		if err != nil {
+			if e.logLevel >= LogInfo {
+				log.Printf("[提取] 规则 %s 提取变量 %s 失败: %v", ruleName, name, err)
+			}
+			continue
+		}
+		e.evaluator.SetVariable(name, val)
+	}
+}
+
+// checkWAF 检查响应是否为 WAF 拦截页，命中时标记本次执行被拦截
+func (e *Engine) checkWAF(ruleName string, response *Response) {
+	signatures := append(append([]string{}, e.config.WAFSignatures...), e.wafSignatures...)
+	if sig, ok := matchWAFSignature(response, signatures); ok {
+		e.blockedByWAF = true
+		if e.logLevel >= LogInfo {
+			log.Printf("[WAF] 规则 %s 的响应命中拦截特征: %s", ruleName, sig)
+		}
+	}
+}
+
+// matchWAFSignature 返回响应中出现的第一个拦截特征：响应体区分大小写，响应头（"名称: 值"）不区分大小写
+func matchWAFSignature(response *Response, signatures []string) (string, bool) {
+	if sig, ok := response.containsAny(signatures); ok {
+		return sig, true
+	}
+	for _, sig := range signatures {
+		if sig == "" {
+			continue
+		}
+		lower := strings.ToLower(sig)
+		for _, line := range headerLines(response.Headers) {
+			if strings.Contains(strings.ToLower(line), lower) {
+				return sig, true
+			}
+		}
+	}
+	return "", false
+}
+
+// jsonBody 解析 json_body 中字符串的变量引用后编码为 JSON
+func (e *Engine) jsonBody(body map[string]interface{}) (string, error) {
+	data, err := json.Marshal(mapStrings(body, e.resolveTemplate))
+	if err != nil {
+		return "", fmt.Errorf("编码 json_body 失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// withDefaultHeader 请求头中没有 name（不区分大小写）时返回加上该请求头的副本
+func withDefaultHeader(headers map[string]string, name, value string) map[string]string {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return headers
+		}
+	}
+	merged := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		merged[k] = v
+	}
+	merged[name] = value
+	return merged
+}
+
+// resolveRawHeaders 返回解析了变量引用的原始请求头副本
+func (e *Engine) resolveRawHeaders(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	resolved := make([]string, len(lines))
+	for i, line := range lines {
+		resolved[i] = e.resolveTemplate(line)
+	}
+	return resolved
+}
+
+// resolveHeaders 返回解析了变量引用的请求头副本，名称和值中的 {{name}} 都会替换
+func (e *Engine) resolveHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	resolved := make(map[string]string, len(headers))
+	for k, v := range headers {
+		resolved[e.resolveTemplate(k)] = e.resolveTemplate(v)
+	}
+	return resolved
+}
+
 // evaluateMainExpression 评估主表达式（如 "r0() && r1() && r2()" 或 "r0 && r1"）
 func (e *Engine) evaluateMainExpression(expr string) (bool, error) {
 	// 移除注释
 	expr = e.removeComments(expr)
 	expr = strings.TrimSpace(expr)
 
-	// 先替换规则调用（如 r0()）为规则结果
+	// 按优先级和括号逐项求值，短路时不再处理（惰性模式下不再执行）后续规则
+	return evaluateBoolean(expr, e.evaluateMainTerm)
+}
+
+// evaluateMainTerm 评估主表达式中的单项
+// 规则引用替换为规则结果，其余内容（如 version == '1.2.3'）交给表达式评估器，使用累积的变量上下文
+func (e *Engine) evaluateMainTerm(term string) (bool, error) {
+	if matches := atLeastRegex.FindStringSubmatch(strings.TrimSpace(term)); matches != nil {
+		return e.evaluateAtLeast(matches[1], matches[2])
+	}
+
+	term, err := e.substituteRules(term)
+	if err != nil {
+		return false, err
+	}
+
+	switch term {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return e.evaluator.Evaluate(term, nil, e.httpClient.GetStoredCookie())
+}
+
+// atLeastRegex 匹配 atleast(n, r0, r1, ...)
+var atLeastRegex = regexp.MustCompile(`^atleast\(\s*(\d+)\s*,(.+)\)$`)
+
+// evaluateAtLeast 至少 n 条规则成功时返回 true，结果已确定时不再处理（惰性模式下不再执行）剩余规则
+func (e *Engine) evaluateAtLeast(n, args string) (bool, error) {
+	threshold, err := strconv.Atoi(n)
+	if err != nil {
+		return false, fmt.Errorf("atleast 的数量无效: %s", n)
+	}
+
+	names := strings.Split(args, ",")
+	for i, name := range names {
+		name = strings.TrimSuffix(strings.TrimSpace(name), "()")
+		if _, ok := e.config.Rules[name]; !ok {
+			return false, fmt.Errorf("atleast 引用了不存在的规则: %s", name)
+		}
+		names[i] = name
+	}
+
+	matched := 0
+	for i, name := range names {
+		if matched >= threshold {
+			break
+		}
+		if matched+len(names)-i < threshold {
+			break
+		}
+		ok, err := e.ruleValue(name)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched >= threshold, nil
+}
+
+// substituteRules 将表达式片段中的规则引用替换为 true/false
+func (e *Engine) substituteRules(expr string) (string, error) {
+	var ruleErr error
+	lookup := func(ruleName string) string {
+		result, err := e.ruleValue(ruleName)
+		if err != nil && ruleErr == nil {
+			ruleErr = err
+		}
+		if result {
+			return "true"
+		}
+		return "false"
+	}
+
+	// 先替换规则调用（如 r0()）为规则结果，只处理已定义的规则，其他函数调用保持原样
 	re1 := regexp.MustCompile(`(\w+)\(\)`)
-	expr = re1.ReplaceAllStringFunc(expr, func(match string) string {
+	expr = replaceOutsideQuotes(re1, expr, func(match string) (string, bool) {
 		ruleName := strings.TrimSuffix(match, "()")
-		if result, ok := e.ruleResults[ruleName]; ok {
-			if result {
-				return "true"
-			}
-			return "false"
+		if _, ok := e.config.Rules[ruleName]; !ok {
+			return match, false
 		}
-		return "false"
+		return lookup(ruleName), true
 	})
 
 	// 再处理简写格式（如 r0 或 r1）
 	// 查找所有规则名（r 开头后跟数字），但要避免替换已替换的值
 	re2 := regexp.MustCompile(`\b(r\d+)\b`)
-	expr = re2.ReplaceAllStringFunc(expr, func(match string) string {
-		if result, ok := e.ruleResults[match]; ok {
-			if result {
-				return "true"
-			}
-			return "false"
-		}
-		return "false"
+	expr = replaceOutsideQuotes(re2, expr, func(match string) (string, bool) {
+		return lookup(match), true
 	})
 
-	// 评估简化后的表达式
-	if strings.Contains(expr, "&&") {
-		parts := strings.Split(expr, "&&")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if part == "false" {
-				return false, nil
-			}
+	return strings.TrimSpace(expr), ruleErr
+}
+
+// replaceOutsideQuotes 替换不在引号内、且后面不紧跟 "." 的匹配项（如 r0.response 不视为规则引用）
+func replaceOutsideQuotes(re *regexp.Regexp, s string, replace func(string) (string, bool)) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if inQuotes(s, start) || (end < len(s) && s[end] == '.') {
+			continue
 		}
-		return true, nil
+		repl, ok := replace(s[start:end])
+		if !ok {
+			continue
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString(repl)
+		last = end
 	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
 
-	if strings.Contains(expr, "||") {
-		parts := strings.Split(expr, "||")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if part == "true" {
-				return true, nil
-			}
+// inQuotes 判断位置 idx 是否处于引号内
+func inQuotes(s string, idx int) bool {
+	var quote byte
+	for i := 0; i < idx; i++ {
+		switch {
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote == 0 && (s[i] == '\'' || s[i] == '"'):
+			quote = s[i]
 		}
-		return false, nil
 	}
-
-	// 单个值
-	return expr == "true", nil
+	return quote != 0
 }
 
 func (e *Engine) removeComments(s string) string {
@@ -189,12 +840,61 @@ func (e *Engine) removeComments(s string) string {
 }
 
 
+// recordResult 根据本次执行情况生成结构化结果
+func (e *Engine) recordResult(start time.Time, matched bool, err error) {
+	rules := make(map[string]bool, len(e.ruleResults))
+	for name, ok := range e.ruleResults {
+		rules[name] = ok
+	}
+
+	var skipped []string
+	for _, name := range e.config.RuleNames() {
+		if e.ruleSkipped[name] {
+			skipped = append(skipped, name)
+		}
+	}
+
+	outputs := e.evaluator.Variables()
+
+	e.result = Result{
+		Name:     e.config.Name,
+		CVEID:    e.config.CVEID,
+		Category: normalizeCategory(e.config.Category),
+		Severity: e.config.Severity(),
+		Source:   e.config.Source,
+		Summary:  e.config.S1,
+		Score:    e.score(),
+		BlockedByWAF: e.blockedByWAF,
+		Target:   e.baseURL,
+		Matched:  matched,
+		Rules:    rules,
+		Skipped:  skipped,
+		Outputs:  outputs,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		e.result.Error = err.Error()
+		e.result.ErrorType = errorType(err)
+	}
+}
+
+// Result 获取最近一次执行的结构化结果
+func (e *Engine) Result() Result {
+	return e.result
+}
+
 // GetRuleResult 获取规则执行结果
 func (e *Engine) GetRuleResult(ruleName string) (bool, bool) {
 	result, ok := e.ruleResults[ruleName]
 	return result, ok
 }
 
+// IsRuleSkipped 判断规则是否因前置条件不满足而被跳过
+func (e *Engine) IsRuleSkipped(ruleName string) bool {
+	return e.ruleSkipped[ruleName]
+}
+
 // GetAllRuleResults 获取所有规则执行结果
 func (e *Engine) GetAllRuleResults() map[string]bool {
 	return e.ruleResults
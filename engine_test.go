@@ -0,0 +1,1669 @@
+package sdk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mustLoadConfig 从 YAML 文本加载配置，失败时终止测试
+func mustLoadConfig(t *testing.T, data string) *POCConfig {
+	t.Helper()
+	config, err := LoadConfigBytes([]byte(data))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	return config
+}
+
+func TestExecuteRerunsRulesInLazyMode(t *testing.T) {
+	var hits, status int32 = 0, http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: rerun
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+	engine.SetLazy(true)
+
+	if matched, err := engine.Execute(); err != nil || matched {
+		t.Fatalf("第一次执行 = %v, %v，期望 false", matched, err)
+	}
+	atomic.StoreInt32(&status, http.StatusOK)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("第二次执行 = %v, %v，期望 true", matched, err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("请求次数 = %d，期望 2", got)
+	}
+}
+
+func TestExecuteRuleClearsSkipped(t *testing.T) {
+	var status int32 = http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: skipped
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /
+    condition: r0()
+    expression: response.status == 200
+`), server.URL)
+
+	if matched, _ := engine.Execute(); matched {
+		t.Fatal("r0 失败时不应匹配")
+	}
+	if !engine.IsRuleSkipped("r1") {
+		t.Fatal("r0 失败时 r1 应被跳过")
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	if _, err := engine.ExecuteRule("r0"); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := engine.ExecuteRule("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rr.Matched || rr.Skipped {
+		t.Errorf("ExecuteRule(r1) = %+v，期望命中且未跳过", rr)
+	}
+	if matched, _ := engine.Execute(); !matched {
+		t.Error("第二次执行期望匹配")
+	}
+	if skipped := engine.Result().Skipped; len(skipped) != 0 {
+		t.Errorf("Result().Skipped = %v，期望为空", skipped)
+	}
+}
+
+func TestUseCookieVariable(t *testing.T) {
+	var cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte("session=s3cr3t"))
+		case "/admin":
+			cookie = r.Header.Get("Cookie")
+			if cookie == "session=s3cr3t" {
+				w.Write([]byte("welcome"))
+			}
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: use-cookie
+rules:
+  r0:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('session=(?P<session>\w+)')
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /admin
+    use_cookie: "session={{session}}"
+    expression: response.body.contains('welcome')
+expression: r0() && r1()
+`), server.URL)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v，服务器收到的 Cookie: %q", matched, err, cookie)
+	}
+}
+
+func TestConditionSkipsRule(t *testing.T) {
+	paths := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths[r.URL.Path]++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: condition
+rules:
+  r0:
+    method: GET
+    path: /probe
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /expensive
+    condition: r0
+    expression: response.status == 404
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || matched {
+		t.Fatalf("Execute() = %v, %v，期望 false", matched, err)
+	}
+	if paths["/expensive"] != 0 {
+		t.Errorf("r1 被跳过时不应发送请求，实际 %d 次", paths["/expensive"])
+	}
+	if !engine.IsRuleSkipped("r1") || engine.IsRuleSkipped("r0") {
+		t.Errorf("跳过状态: r0=%v r1=%v", engine.IsRuleSkipped("r0"), engine.IsRuleSkipped("r1"))
+	}
+	if skipped := engine.Result().Skipped; len(skipped) != 1 || skipped[0] != "r1" {
+		t.Errorf("Result().Skipped = %v", skipped)
+	}
+}
+
+func TestPayloadsMatchAnyIteration(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path+"|"+r.Header.Get("X-Probe"))
+		if r.URL.Path != "/backup.zip" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: payloads
+rules:
+  r0:
+    method: GET
+    path: /{{payload}}
+    headers:
+      X-Probe: "{{payload}}"
+    payloads: ["admin", "backup.zip", "never"]
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	want := []string{"/admin|admin", "/backup.zip|backup.zip"}
+	if len(requested) != len(want) || requested[0] != want[0] || requested[1] != want[1] {
+		t.Errorf("请求 = %v，期望命中后停止: %v", requested, want)
+	}
+	if got := engine.Result().Outputs["r0.payload"]; got != "backup.zip" {
+		t.Errorf("outputs[r0.payload] = %q", got)
+	}
+}
+
+func TestRuleBasicAndBearerAuth(t *testing.T) {
+	auth := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth[r.URL.Path] = r.Header.Get("Authorization")
+		if r.URL.Path == "/token" {
+			w.Write([]byte("token=t0k3n"))
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: auth
+rules:
+  r0:
+    method: GET
+    path: /basic
+    basic_auth:
+      user: admin
+      pass: secret
+  r1:
+    method: GET
+    path: /token
+    extract_cookie: response.body.extract('token=(?P<token>\w+)')
+  r2:
+    method: GET
+    path: /bearer
+    bearer_token: "{{token}}"
+  r3:
+    method: GET
+    path: /override
+    bearer_token: ignored
+    headers:
+      Authorization: "Custom xyz"
+`), server.URL)
+
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"/basic":    "Basic YWRtaW46c2VjcmV0",
+		"/bearer":   "Bearer t0k3n",
+		"/override": "Custom xyz",
+	}
+	for path, header := range want {
+		if auth[path] != header {
+			t.Errorf("%s 的 Authorization = %q，期望 %q", path, auth[path], header)
+		}
+	}
+}
+
+func TestLazyModeShortCircuits(t *testing.T) {
+	paths := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths[r.URL.Path]++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: lazy
+rules:
+  r0:
+    method: GET
+    path: /r0
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /r1
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+	engine.SetLazy(true)
+
+	if matched, err := engine.Execute(); err != nil || matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	if paths["/r0"] != 1 || paths["/r1"] != 0 {
+		t.Errorf("请求次数 = %v，r0 失败时 r1 不应被请求", paths)
+	}
+}
+
+func TestLazyModeRunsCookieRulesFirst(t *testing.T) {
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, r.URL.Path)
+		if r.URL.Path == "/login" {
+			w.Header().Set("Set-Cookie", "sid=1")
+			return
+		}
+		if r.Header.Get("Cookie") == "" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: lazy-cookie
+rules:
+  login:
+    method: GET
+    path: /login
+    extract_cookie: response.headers.get('Set-Cookie')
+  admin:
+    method: GET
+    path: /admin
+    use_cookie: response.extracted_cookie
+    expression: response.status == 200
+expression: admin()
+`), server.URL)
+	engine.SetLazy(true)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v，请求顺序 %v", matched, err, order)
+	}
+	if len(order) != 2 || order[0] != "/login" {
+		t.Errorf("请求顺序 = %v，期望先执行 login", order)
+	}
+}
+
+func TestLazyModeRunsVariableRulesFirst(t *testing.T) {
+	var order, tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, r.URL.Path)
+		switch r.URL.Path {
+		case "/token":
+			fmt.Fprint(w, `{"token": "abc123"}`)
+		case "/session":
+			fmt.Fprint(w, "sid=s42")
+		default:
+			tokens = append(tokens, r.Header.Get("X-Token")+" "+r.URL.Query().Get("sid"))
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: lazy-variables
+rules:
+  r0:
+    method: GET
+    path: /token
+    extractors:
+      token: response.body.json('$.token')
+  unrelated:
+    method: GET
+    path: /unrelated
+  session:
+    method: GET
+    path: /session
+    extract_cookie: response.body.extract('sid=(?P<sid>\w+)')
+  r1:
+    method: GET
+    path: /api?sid={{sid}}
+    headers:
+      X-Token: "{{token}}"
+    expression: response.status == 200
+expression: r1()
+`), server.URL)
+	engine.SetLazy(true)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v，请求顺序 %v", matched, err, order)
+	}
+	// 只执行提供变量的规则，不执行无关规则
+	if !reflect.DeepEqual(order, []string{"/token", "/session", "/api"}) {
+		t.Errorf("请求顺序 = %v，期望先执行 r0 和 session", order)
+	}
+	if len(tokens) != 1 || tokens[0] != "abc123 s42" {
+		t.Errorf("r1 收到 %q，期望已解析的变量", tokens)
+	}
+}
+
+func TestNamedGroupsBecomeVariables(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte("user=alice token=abc123"))
+			return
+		}
+		got = r.URL.RawQuery
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: named-groups
+rules:
+  r0:
+    method: GET
+    path: /info
+    extract_cookie: response.body.extract('user=(?P<user>\w+) token=(?P<token>\w+)')
+  r1:
+    method: GET
+    path: /api?u={{user}}&t={{token}}
+`), server.URL)
+
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got != "u=alice&t=abc123" {
+		t.Errorf("r1 的查询参数 = %q", got)
+	}
+	if outputs := engine.Result().Outputs; outputs["user"] != "alice" || outputs["token"] != "abc123" {
+		t.Errorf("outputs = %v", outputs)
+	}
+}
+
+func TestDeadlineBoundsWholeRun(t *testing.T) {
+	paths := map[string]int{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path != "/fast" {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: deadline
+rules:
+  r0:
+    method: GET
+    path: /fast
+  r1:
+    method: GET
+    path: /slow1
+  r2:
+    method: GET
+    path: /slow2
+`), server.URL)
+	engine.SetDeadline(300 * time.Millisecond)
+
+	start := time.Now()
+	_, err := engine.Execute()
+	if err == nil {
+		t.Fatal("超出总时限时期望返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("执行耗时 %v，应在总时限后尽快结束", elapsed)
+	}
+
+	result := engine.Result()
+	if !result.Rules["r0"] || result.Error == "" {
+		t.Errorf("部分结果 = rules %v error %q", result.Rules, result.Error)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if paths["/slow2"] != 0 {
+		t.Errorf("超出总时限后不应再执行 r2")
+	}
+}
+
+func TestMainExpressionComparesVariables(t *testing.T) {
+	server := textServer(t, "version=1.2.3")
+	config := `
+name: main-vars
+rules:
+  r0:
+    method: GET
+    path: /
+    extract_cookie: response.body.extract('version=(?P<version>[\d.]+)')
+    expression: response.status == 200
+expression: %s
+`
+	for expr, want := range map[string]bool{
+		"r0 && version == '1.2.3'":   true,
+		"r0() && version == '2.0.0'": false,
+		"version == '2.0.0' || r0":   true,
+		"r0 && version < '1.10.0'":   true,
+	} {
+		engine := NewEngine(mustLoadConfig(t, fmt.Sprintf(config, `"`+expr+`"`)), server.URL)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if matched != want {
+			t.Errorf("%s = %v，期望 %v", expr, matched, want)
+		}
+	}
+}
+
+func TestCompareAcrossRuleResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/before" {
+			w.Write([]byte("token=1"))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("token=1 and more data"))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: cross-rule
+rules:
+  r0:
+    method: GET
+    path: /before
+  r1:
+    method: GET
+    path: /after
+    expression: r0.response.body.contains('token') && response.body.length > r0.response.body.length && r0.response.status == 200 && response.status == 201
+expression: r1()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+}
+
+func TestSetTargetDoesNotLeakState(t *testing.T) {
+	type seen struct{ cookie, session string }
+	newServer := func(session string, got *seen) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/login":
+				if session != "" {
+					w.Write([]byte("session=" + session))
+				}
+			case "/whoami":
+				got.cookie = r.Header.Get("Cookie")
+				got.session = r.Header.Get("X-Session")
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	var seenA, seenB seen
+	serverA := newServer("alpha", &seenA)
+	serverB := newServer("", &seenB)
+
+	engine := NewEngineFromConfig(mustLoadConfig(t, `
+name: reuse
+rules:
+  r0:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('session=(?P<session>\w+)')
+    expression: response.body.contains('session=')
+  r1:
+    method: GET
+    path: /whoami
+    use_cookie: response.extracted_cookie
+    headers:
+      X-Session: "{{session}}"
+    expression: response.status == 200
+expression: r0() && r1()
+`))
+
+	engine.SetTarget(serverA.URL)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("目标 A: Execute() = %v, %v", matched, err)
+	}
+	if seenA.cookie != "alpha" || seenA.session != "alpha" {
+		t.Fatalf("目标 A 收到 %+v", seenA)
+	}
+
+	engine.SetTarget(serverB.URL)
+	if matched, err := engine.Execute(); err != nil || matched {
+		t.Fatalf("目标 B: Execute() = %v, %v，期望不匹配", matched, err)
+	}
+	if seenB.cookie != "" || seenB.session == "alpha" {
+		t.Errorf("目标 A 的状态泄漏到目标 B: %+v", seenB)
+	}
+	if ok, _ := engine.GetRuleResult("r0"); ok {
+		t.Error("目标 B 的 r0 不应沿用目标 A 的结果")
+	}
+}
+
+func TestResetClearsState(t *testing.T) {
+	server := textServer(t, "session=alpha")
+	engine := NewEngine(mustLoadConfig(t, `
+name: reset
+rules:
+  r0:
+    method: GET
+    path: /
+    extract_cookie: response.body.extract('session=(?P<session>\w+)')
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	engine.Reset()
+
+	if results := engine.GetAllRuleResults(); len(results) != 0 {
+		t.Errorf("规则结果未清空: %v", results)
+	}
+	if cookie := engine.HTTPClient().GetStoredCookie(); cookie != "" {
+		t.Errorf("Cookie 未清空: %q", cookie)
+	}
+	if got := engine.resolveTemplate("{{session}}"); got != "{{session}}" {
+		t.Errorf("变量未清空: {{session}} = %q", got)
+	}
+	if r := engine.Result(); r.Matched || len(r.Rules) != 0 {
+		t.Errorf("Result() 未清空: %+v", r)
+	}
+}
+
+func TestExecuteRuleStandalone(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: single
+rules:
+  r0:
+    method: GET
+    path: /first
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /second
+    expression: response.status == 201
+expression: r0() && r1()
+`), server.URL)
+
+	rr, err := engine.ExecuteRule("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Name != "r1" || !rr.Matched || rr.Skipped {
+		t.Errorf("ExecuteRule(r1) = %+v", rr)
+	}
+	if rr.Response == nil || rr.Response.Status != http.StatusCreated || rr.Response.Body != "created /second" {
+		t.Fatalf("Response = %+v", rr.Response)
+	}
+	if len(paths) != 1 || paths[0] != "/second" {
+		t.Errorf("服务器收到的请求 = %v，期望只有 /second", paths)
+	}
+
+	if _, err := engine.ExecuteRule("r9"); err == nil {
+		t.Error("不存在的规则应返回错误")
+	}
+}
+
+func TestRequestDumpShowsResolvedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte("token=t0k3n"))
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: dump
+rules:
+  r0:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('token=(?P<token>\w+)')
+    expression: response.status == 200
+  r1:
+    method: POST
+    path: /api?t={{token}}
+    use_cookie: "sid={{token}}"
+    headers:
+      X-Token: "{{token}}"
+    body:
+      - "token={{token}}"
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	rr, err := engine.ExecuteRule("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := rr.Response.RequestDump
+	for _, want := range []string{"POST /api?t=t0k3n HTTP/1.1", "X-Token: t0k3n", "Cookie: sid=t0k3n", "\r\n\r\ntoken=t0k3n"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("RequestDump 缺少 %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "{{token}}") {
+		t.Errorf("RequestDump 含有未替换的变量:\n%s", dump)
+	}
+}
+
+func TestMainExpressionPrecedence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// r0、r2 成功，r1 失败
+	config := mustLoadConfig(t, `
+name: precedence
+rules:
+  r0:
+    method: GET
+    path: /ok
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /fail
+    expression: response.status == 200
+  r2:
+    method: GET
+    path: /ok
+    expression: response.status == 200
+`)
+
+	for expr, want := range map[string]bool{
+		"r0 && r1 || r2":         true,
+		"r1 && r0 || r1":         false,
+		"r0 || r1 && r1":         true, // 从左到右求值会得到 false
+		"(r0 || r1) && r2":       true,
+		"(r0 || r1) && r1":       false,
+		"r1 || (r0 && r2)":       true,
+		"((r1 || r0)) && r2":     true,
+		"r0() && (r1() || r2())": true,
+	} {
+		config.Expression = expr
+		matched, err := NewEngine(config, server.URL).Execute()
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if matched != want {
+			t.Errorf("%s = %v，期望 %v", expr, matched, want)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// r0、r2 成功，r1、r3 失败
+	config := mustLoadConfig(t, `
+name: atleast
+rules:
+  r0:
+    method: GET
+    path: /ok
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /fail
+    expression: response.status == 200
+  r2:
+    method: GET
+    path: /ok
+    expression: response.status == 200
+  r3:
+    method: GET
+    path: /fail
+    expression: response.status == 200
+`)
+
+	for expr, want := range map[string]bool{
+		"atleast(2, r0, r1, r2, r3)":           true,
+		"atleast(3, r0, r1, r2, r3)":           false,
+		"atleast(1, r1(), r3())":               false,
+		"atleast(0, r1)":                       true,
+		"atleast(2, r0, r2) && r1 || r2":       true,
+		"atleast(2, r0, r1) || atleast(1, r3)": false,
+	} {
+		config.Expression = expr
+		matched, err := NewEngine(config, server.URL).Execute()
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if matched != want {
+			t.Errorf("%s = %v，期望 %v", expr, matched, want)
+		}
+	}
+
+	// 惰性模式下结果确定后不再执行剩余规则
+	config.Expression = "atleast(2, r0, r2, r1, r3)"
+	engine := NewEngine(config, server.URL)
+	engine.SetLazy(true)
+	atomic.StoreInt32(&hits, 0)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("惰性模式 Execute() = %v, %v", matched, err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("惰性模式发送了 %d 个请求，期望达到阈值后停止（2 个）", got)
+	}
+}
+
+func TestEngineRegisterFunc(t *testing.T) {
+	server := textServer(t, "token=dXNlcjphZG1pbg")
+	engine := NewEngine(mustLoadConfig(t, `
+name: custom-func
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: decode_token(response.body, 'token=') == 'user:admin'
+  r1:
+    method: GET
+    path: /
+    expression: undefined_func(response.body) == 'x'
+expression: r0() && decode_token('token=YQ', 'token=') == 'a'
+`), server.URL)
+	engine.RegisterFunc("decode_token", func(args []string) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("参数个数 = %d", len(args))
+		}
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(args[0], args[1]))
+		return string(data), err
+	})
+
+	rr, err := engine.ExecuteRule("r0")
+	if err != nil || !rr.Matched {
+		t.Errorf("ExecuteRule(r0) = %+v, %v，期望自定义函数的结果参与比较", rr, err)
+	}
+	if rr, err := engine.ExecuteRule("r1"); err == nil && rr.Matched {
+		t.Error("未注册的函数不应匹配")
+	}
+	if matched, err := engine.evaluateMainExpression(engine.config.Expression); err != nil || !matched {
+		t.Errorf("主表达式 = %v, %v，期望可以使用自定义函数", matched, err)
+	}
+}
+
+func TestRepeatWithDelay(t *testing.T) {
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) >= 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: repeat
+rules:
+  r0:
+    method: POST
+    path: /login
+    repeat: 3
+    repeat_delay: 50
+    expression: response.status == 429
+expression: r0() && r0.statuses == '200,200,429'
+`), server.URL)
+	// 重复的请求不应被响应缓存合并
+	engine.HTTPClient().EnableCache(true)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	if len(times) != 3 {
+		t.Fatalf("请求次数 = %d，期望 3", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 50*time.Millisecond {
+			t.Errorf("第 %d 次与上一次请求间隔 %v，期望不少于 50ms", i+1, gap)
+		}
+	}
+}
+
+func TestRuleCallbacksFireInOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: callbacks
+rules:
+  r0:
+    method: GET
+    path: /ok
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /fail
+    expression: response.status == 200
+  r2:
+    method: GET
+    path: /ok
+    condition: r1()
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+
+	var events []string
+	engine.OnRuleStart(func(name string) {
+		events = append(events, "start "+name)
+	})
+	engine.OnRuleComplete(func(r RuleResult) {
+		events = append(events, fmt.Sprintf("done %s matched=%v skipped=%v", r.Name, r.Matched, r.Skipped))
+	})
+
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"start r0", "done r0 matched=true skipped=false",
+		"start r1", "done r1 matched=false skipped=false",
+		"start r2", "done r2 matched=false skipped=true",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("回调顺序 = %q，期望 %q", events, want)
+	}
+}
+
+func TestContainsVariableDetectsReflection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/marker":
+			fmt.Fprintf(w, "gp%d", time.Now().UnixNano())
+		case "/reflect":
+			fmt.Fprintf(w, "<p>搜索: %s</p>", r.URL.Query().Get("q"))
+		case "/escape":
+			fmt.Fprint(w, "<p>搜索: ***</p>")
+		}
+	}))
+	defer server.Close()
+
+	for path, want := range map[string]bool{"/reflect": true, "/escape": false} {
+		engine := NewEngine(mustLoadConfig(t, `
+name: reflect
+rules:
+  r0:
+    method: GET
+    path: /marker
+    extractors:
+      marker: response.body
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: `+path+`?q={{marker}}
+    expression: response.body.contains({{marker}})
+  r2:
+    method: GET
+    path: `+path+`?q={{marker}}
+    expression: response.body.not_contains({{marker}})
+expression: r0() && r1()
+`), server.URL)
+
+		if _, err := engine.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		r1, _ := engine.GetRuleResult("r1")
+		r2, _ := engine.GetRuleResult("r2")
+		if r1 != want || r2 == want {
+			t.Errorf("%s: contains = %v, not_contains = %v，期望 contains = %v", path, r1, r2, want)
+		}
+	}
+}
+
+func TestNoCookiesSendsNoCookieHeader(t *testing.T) {
+	cookies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte("session=s3cr3t"))
+			return
+		}
+		cookies[r.URL.Path] = r.Header.Get("Cookie")
+		if r.Header.Get("Cookie") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: no-cookies
+rules:
+  r0:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('(session=\w+)')
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /authed
+    use_cookie: response.extracted_cookie
+    expression: response.status == 200
+  r2:
+    method: GET
+    path: /anonymous
+    use_cookie: response.extracted_cookie
+    no_cookies: true
+    expression: response.status == 401
+expression: r0() && r1() && r2()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v，服务器收到的 Cookie: %v", matched, err, cookies)
+	}
+	if cookies["/authed"] != "session=s3cr3t" {
+		t.Errorf("/authed 收到的 Cookie = %q", cookies["/authed"])
+	}
+	if got, ok := cookies["/anonymous"]; !ok || got != "" {
+		t.Errorf("no_cookies 时不应发送 Cookie，收到 %q", got)
+	}
+}
+
+func TestMaxRequestsStopsPayloadLoop(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: max-requests
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /item?id={{payload}}
+    payload_generator: "range:1-100"
+    expression: response.status == 500
+expression: r0() && r1()
+`), server.URL)
+	engine.SetMaxRequests(4)
+
+	matched, err := engine.Execute()
+	if matched || !errors.Is(err, ErrMaxRequestsExceeded) {
+		t.Fatalf("Execute() = %v, %v，期望 ErrMaxRequestsExceeded", matched, err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 4 {
+		t.Errorf("服务器收到 %d 次请求，期望在上限 4 处停止", got)
+	}
+
+	result := engine.Result()
+	if result.ErrorType != "max_requests" || !result.Rules["r0"] {
+		t.Errorf("Result = %+v，期望 ErrorType 为 max_requests 且保留 r0 的结果", result)
+	}
+
+	// 每次执行重新计数
+	atomic.StoreInt32(&hits, 0)
+	if _, err := engine.Execute(); !errors.Is(err, ErrMaxRequestsExceeded) {
+		t.Fatalf("第二次 Execute() err = %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 4 {
+		t.Errorf("第二次执行服务器收到 %d 次请求，期望 4", got)
+	}
+}
+
+func TestExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, "admin panel")
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: expect-status
+rules:
+  only_ok:
+    method: GET
+    path: /ok
+    expect_status: [200]
+  only_redirect:
+    method: GET
+    path: /redirect
+    max_redirects: 0
+    expect_status: [200, 302]
+  only_missing:
+    method: GET
+    path: /missing
+    expect_status: [200]
+  with_expression:
+    method: GET
+    path: /ok
+    expect_status: [200]
+    expression: response.body.contains('admin')
+  expression_fails:
+    method: GET
+    path: /ok
+    expect_status: [200]
+    expression: response.body.contains('nobody')
+  status_fails:
+    method: GET
+    path: /ok
+    expect_status: [404]
+    expression: response.body.contains('admin')
+`), server.URL)
+
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"only_ok":          true,
+		"only_redirect":    true,
+		"only_missing":     false,
+		"with_expression":  true,
+		"expression_fails": false,
+		"status_fails":     false,
+	} {
+		if got, _ := engine.GetRuleResult(name); got != want {
+			t.Errorf("%s = %v，期望 %v", name, got, want)
+		}
+	}
+}
+
+func TestWeightedScore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Server", "WebLogic 12")
+		case "/console":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	const rules = `
+rules:
+  r0:
+    method: GET
+    path: /
+    weight: 3
+    expression: response.headers.get('Server').contains('WebLogic')
+  r1:
+    method: GET
+    path: /console
+    weight: 2
+    expect_status: [200]
+  r2:
+    method: GET
+    path: /wls-wsat
+    weight: 4
+    expect_status: [200]
+`
+	tests := []struct {
+		extra   string
+		matched bool
+	}{
+		{"threshold: 5", true},
+		{"threshold: 6", false},
+		{"threshold: 5\nexpression: r2()", false},
+		{"threshold: 5\nexpression: r0() && r1()", true},
+	}
+	for _, tt := range tests {
+		engine := NewEngine(mustLoadConfig(t, "name: score\n"+tt.extra+rules), server.URL)
+		engine.SetLazy(true)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != tt.matched {
+			t.Errorf("%q: matched = %v，期望 %v", tt.extra, matched, tt.matched)
+		}
+		// 打分模式下惰性模式也执行全部规则，r0 和 r1 成功
+		if score := engine.Result().Score; score != 5 {
+			t.Errorf("%q: Score = %d，期望 3 + 2 = 5", tt.extra, score)
+		}
+	}
+}
+
+func TestAbsoluteRulePathHitsOtherServer(t *testing.T) {
+	var targetPaths, callbackPaths []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetPaths = append(targetPaths, r.URL.Path)
+		fmt.Fprint(w, "target")
+	}))
+	defer target.Close()
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbackPaths = append(callbackPaths, r.URL.RequestURI())
+		fmt.Fprint(w, "hits=1")
+	}))
+	defer callback.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: absolute-path
+rules:
+  r0:
+    method: GET
+    path: /trigger
+    expression: response.body == 'target'
+  r1:
+    method: GET
+    path: `+callback.URL+`/poll?id=42
+    expression: response.body.contains('hits=1')
+expression: r0() && r1()
+`), target.URL+"/app")
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	if !reflect.DeepEqual(targetPaths, []string{"/app/trigger"}) {
+		t.Errorf("目标服务器收到 %v，期望只有 /app/trigger", targetPaths)
+	}
+	if !reflect.DeepEqual(callbackPaths, []string{"/poll?id=42"}) {
+		t.Errorf("回连服务器收到 %v，期望 /poll?id=42", callbackPaths)
+	}
+}
+
+func TestMatchFirstBytes(t *testing.T) {
+	body := "\x89PNG" + strings.Repeat("x", 100) + "MARKER"
+	server := textServer(t, body)
+
+	cases := []struct {
+		expression string
+		want       bool
+	}{
+		{"response.body.contains('PNG')", true},
+		{"response.body.contains('MARKER')", false},
+	}
+	for _, c := range cases {
+		engine := NewEngine(mustLoadConfig(t, `
+name: first-bytes
+rules:
+  r0:
+    method: GET
+    path: /
+    match_first_bytes: 16
+    expression: `+c.expression+`
+expression: r0()
+`), server.URL)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatalf("%s: %v", c.expression, err)
+		}
+		if matched != c.want {
+			t.Errorf("%s = %v，期望 %v", c.expression, matched, c.want)
+		}
+	}
+
+	// 不设置 match_first_bytes 时检查完整响应体
+	engine := NewEngine(mustLoadConfig(t, `
+name: full-body
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.body.contains('MARKER')
+expression: r0()
+`), server.URL)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Errorf("未限制时 Execute() = %v, %v，期望匹配", matched, err)
+	}
+}
+
+func TestJSONBody(t *testing.T) {
+	var gotBody, gotType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotBody, gotType = string(data), r.Header.Get("Content-Type")
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	for _, c := range []struct {
+		headers  string
+		wantType string
+	}{
+		{"", "application/json"},
+		{"    headers:\n      content-type: application/vnd.api+json\n", "application/vnd.api+json"},
+	} {
+		engine := NewEngine(mustLoadConfig(t, `
+name: json-body
+rules:
+  r0:
+    method: POST
+    path: /api
+`+c.headers+`    json_body:
+      user: "{{user}}"
+      age: 3
+      tags: [a, "{{user}}"]
+      profile:
+        admin: true
+    expression: response.body == 'ok'
+expression: r0()
+`), server.URL)
+		engine.evaluator.SetVariable("user", "alice")
+		if matched, err := engine.Execute(); err != nil || !matched {
+			t.Fatalf("Execute() = %v, %v", matched, err)
+		}
+
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(gotBody), &decoded); err != nil {
+			t.Fatalf("请求体不是合法的 JSON: %q", gotBody)
+		}
+		want := map[string]interface{}{
+			"user":    "alice",
+			"age":     float64(3),
+			"tags":    []interface{}{"a", "alice"},
+			"profile": map[string]interface{}{"admin": true},
+		}
+		if !reflect.DeepEqual(decoded, want) {
+			t.Errorf("请求体 = %v，期望 %v", decoded, want)
+		}
+		if gotType != c.wantType {
+			t.Errorf("Content-Type = %q，期望 %q", gotType, c.wantType)
+		}
+	}
+}
+
+func TestBlockedByWAF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/body":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "Request blocked by SafeLine")
+		case "/header":
+			w.Header().Set("Server", "Cloudflare-WAF")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			fmt.Fprint(w, "normal page")
+		}
+	}))
+	defer server.Close()
+
+	config := func(path string) *POCConfig {
+		return mustLoadConfig(t, `
+name: waf
+waf_signatures: ["blocked by SafeLine"]
+rules:
+  r0:
+    method: GET
+    path: `+path+`
+    expression: response.body.contains('vulnerable')
+expression: r0()
+`)
+	}
+
+	for _, c := range []struct {
+		path    string
+		extra   []string
+		wantWAF bool
+	}{
+		{"/body", nil, true},
+		{"/header", nil, false},
+		{"/normal", nil, false},
+		// SetWAFSignatures 的特征与配置中的一起使用
+		{"/header", []string{"server: cloudflare-waf"}, true},
+		{"/normal", []string{"server: cloudflare-waf"}, false},
+	} {
+		engine := NewEngine(config(c.path), server.URL)
+		engine.SetWAFSignatures(c.extra)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := engine.Result().BlockedByWAF; got != c.wantWAF || matched {
+			t.Errorf("%s %v: BlockedByWAF = %v, matched = %v，期望 %v, false", c.path, c.extra, got, matched, c.wantWAF)
+		}
+	}
+}
+
+func TestMatchWAFSignatureCase(t *testing.T) {
+	response := &Response{Status: 403, Headers: http.Header{"X-Waf": {"Blocked"}}, Body: "Access Denied"}
+	for sig, want := range map[string]bool{
+		"Access Denied":  true,
+		"access denied":  false, // 响应体区分大小写
+		"x-waf: blocked": true,  // 响应头不区分大小写
+		"X-WAF: BLOCKED": true,
+		"":               false,
+	} {
+		if _, got := matchWAFSignature(response, []string{sig}); got != want {
+			t.Errorf("matchWAFSignature(%q) = %v，期望 %v", sig, got, want)
+		}
+	}
+}
+
+func TestHeadersResolveVariablesFromPriorRule(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			fmt.Fprint(w, "token=abc123 header=X-Api-Key")
+			return
+		}
+		got = r.Header.Clone()
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: header-template
+rules:
+  r0:
+    method: GET
+    path: /login
+    extract_cookie: response.body.extract('token=(?P<token>\w+) header=(?P<header>[\w-]+)')
+  r1:
+    method: GET
+    path: /api
+    headers:
+      X-Token: "{{token}}"
+      Authorization: Bearer {{token}}
+      "{{header}}": key-{{token}}
+      X-Unknown: "{{missing}}"
+    expression: response.body == 'ok'
+expression: r0() && r1()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	for name, want := range map[string]string{
+		"X-Token":       "abc123",
+		"Authorization": "Bearer abc123",
+		"X-Api-Key":     "key-abc123",
+		"X-Unknown":     "{{missing}}", // 未定义的变量保持原样
+	} {
+		if v := got.Get(name); v != want {
+			t.Errorf("请求头 %s = %q，期望 %q", name, v, want)
+		}
+	}
+}
+
+func TestExtractorsCaptureSeveralVariables(t *testing.T) {
+	var gotPath, gotAuth, gotCSRF string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/login" {
+			w.Header().Set("X-CSRF-Token", "csrf-789")
+			fmt.Fprint(w, `{"user": {"id": 42}, "note": "token=tok456"}`)
+			return
+		}
+		gotPath, gotAuth, gotCSRF = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-CSRF-Token")
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: extractors
+rules:
+  r0:
+    method: POST
+    path: /api/login
+    extractors:
+      uid: response.body.json('$.user.id')
+      token: response.body.extract('token=(\w+)')
+      csrf: response.headers.get('X-CSRF-Token')
+      missing: response.body.json('$.no.such')
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /api/users/{{uid}}
+    headers:
+      Authorization: Bearer {{token}}
+      X-CSRF-Token: "{{csrf}}"
+    expression: response.body == 'ok'
+expression: r0() && r1()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	if gotPath != "/api/users/42" || gotAuth != "Bearer tok456" || gotCSRF != "csrf-789" {
+		t.Errorf("r1 收到 path = %q, Authorization = %q, X-CSRF-Token = %q", gotPath, gotAuth, gotCSRF)
+	}
+	// 提取失败的变量不设置，也不影响规则结果
+	if _, ok := engine.evaluator.GetVariable("missing"); ok {
+		t.Error("提取失败的变量不应设置")
+	}
+}
+
+func TestCompareBodyWithFileReference(t *testing.T) {
+	const reference = "<configuration>\n  <appSettings/>\n</configuration>\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/default/web.config" {
+			fmt.Fprint(w, reference)
+			return
+		}
+		fmt.Fprint(w, "<configuration>\n  <appSettings mode=\"custom\"/>\n</configuration>\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "reference"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "reference"), "web.config", reference)
+
+	for path, want := range map[string]bool{"/default/web.config": true, "/custom/web.config": false} {
+		config, err := LoadConfig(writeFile(t, dir, "poc.yaml", `
+name: file-compare
+rules:
+  r0:
+    method: GET
+    path: `+path+`
+    expression: response.body == @file:reference/web.config
+expression: r0()
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		matched, err := NewEngine(config, server.URL).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != want {
+			t.Errorf("%s: Execute() = %v，期望 %v", path, matched, want)
+		}
+	}
+}
+
+func TestFileReferenceInExpressionRejectsEscape(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "secret.txt", "secret")
+	dir := filepath.Join(root, "pocs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExpressionEvaluator()
+	e.SetBaseDir(dir)
+	response := &Response{Status: 200, Headers: make(http.Header), Body: "secret"}
+	for _, ref := range []string{"../secret.txt", "sub/../../secret.txt", filepath.Join(root, "secret.txt"), "missing.txt"} {
+		if matched, err := e.Evaluate("response.body == @file:"+ref, response, ""); err == nil {
+			t.Errorf("@file:%s = %v，期望返回错误", ref, matched)
+		}
+	}
+}
+
+func TestPOCHeadersMergedIntoRules(t *testing.T) {
+	got := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got[r.URL.Path] = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: global-headers
+headers:
+  User-Agent: gopoc-scanner
+  Accept: "*/*"
+rules:
+  r0:
+    method: GET
+    path: /plain
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /override
+    headers:
+      accept: application/json
+      X-Rule: r1
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+
+	plain, override := got["/plain"], got["/override"]
+	if plain.Get("User-Agent") != "gopoc-scanner" || plain.Get("Accept") != "*/*" {
+		t.Errorf("未设置请求头的规则收到 %v，期望使用 POC 的请求头", plain)
+	}
+	// 规则中同名（不区分大小写）的请求头优先，且只发送一份
+	if v := override.Values("Accept"); len(v) != 1 || v[0] != "application/json" {
+		t.Errorf("覆盖后的 Accept = %v，期望只有 application/json", v)
+	}
+	if override.Get("User-Agent") != "gopoc-scanner" || override.Get("X-Rule") != "r1" {
+		t.Errorf("覆盖规则收到 %v", override)
+	}
+}
+
+func TestMergeHeaders(t *testing.T) {
+	base := map[string]string{"Accept": "*/*", "User-Agent": "ua"}
+	merged := mergeHeaders(base, map[string]string{"ACCEPT": "text/html", "X-A": "1"})
+	if !reflect.DeepEqual(merged, map[string]string{"ACCEPT": "text/html", "User-Agent": "ua", "X-A": "1"}) {
+		t.Errorf("mergeHeaders = %v", merged)
+	}
+	if base["Accept"] != "*/*" || len(base) != 2 {
+		t.Errorf("base 被修改: %v", base)
+	}
+	overrides := map[string]string{"X-B": "2"}
+	if got := mergeHeaders(nil, overrides); !reflect.DeepEqual(got, overrides) {
+		t.Errorf("base 为空时 = %v", got)
+	}
+}
+
+func TestRuleNameAndDescriptionInResults(t *testing.T) {
+	server := textServer(t, "Apache Tomcat/9.0.30")
+	config := mustLoadConfig(t, `
+name: described
+rules:
+  r0:
+    name: 版本探测
+    description: 从默认错误页读取 Tomcat 版本
+    method: GET
+    path: /404
+    expression: response.body.contains('Tomcat')
+  r1:
+    method: GET
+    path: /
+    expression: response.status == 200
+expression: r0() && r1()
+`)
+
+	engine := NewEngine(config, server.URL)
+	var result RuleResult
+	output := captureLog(t, func() {
+		engine.SetLogLevel(LogDebug)
+		var err error
+		if result, err = engine.ExecuteRule("r0"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if result.DisplayName != "版本探测" || result.Description != "从默认错误页读取 Tomcat 版本" || !result.Matched {
+		t.Errorf("RuleResult = %+v", result)
+	}
+	if !strings.Contains(output, "r0（版本探测）: 从默认错误页读取 Tomcat 版本") {
+		t.Errorf("调试日志中缺少规则名称和说明:\n%s", output)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"display_name":"版本探测"`) || !strings.Contains(string(data), `"description":"从默认错误页读取 Tomcat 版本"`) {
+		t.Errorf("JSON = %s", data)
+	}
+
+	// 未设置时为空，JSON 中省略
+	plain, err := NewEngine(config, server.URL).ExecuteRule("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.DisplayName != "" || plain.Description != "" {
+		t.Errorf("未设置名称和说明的 RuleResult = %+v", plain)
+	}
+	if data, _ := json.Marshal(plain); strings.Contains(string(data), "description") {
+		t.Errorf("JSON = %s，期望省略 description", data)
+	}
+}
@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// 网络错误分类，可通过 errors.Is 判断目标不可达的原因
+var (
+	ErrConnRefused = errors.New("连接被拒绝")
+	ErrTimeout     = errors.New("请求超时")
+	ErrDNS         = errors.New("DNS 解析失败")
+	ErrTLS         = errors.New("TLS 握手失败")
+)
+
+// 表达式错误分类，可通过 errors.Is 区分表达式写法错误和请求等运行时错误
+var (
+	ErrUnsupportedExpression = errors.New("不支持的表达式")
+	ErrInvalidComparison     = errors.New("无效的比较表达式")
+	ErrBadRegex              = errors.New("无效的正则表达式")
+)
+
+// ErrMaxRequestsExceeded 请求数超过 SetMaxRequests 设置的上限
+var ErrMaxRequestsExceeded = errors.New("超过最大请求数")
+
+// classifyError 将请求错误归类为上面的分类错误，无法归类时返回 nil
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrConnRefused
+	}
+
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &recordErr) || errors.As(err, &certErr) ||
+		errors.As(err, &unknownAuthErr) || errors.As(err, &hostnameErr) ||
+		strings.Contains(err.Error(), "tls: ") {
+		return ErrTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrTimeout
+	}
+
+	return nil
+}
+
+// errorType 返回错误分类的简短标识，用于结构化结果
+func errorType(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrConnRefused):
+		return "conn_refused"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrDNS):
+		return "dns"
+	case errors.Is(err, ErrTLS):
+		return "tls"
+	case errors.Is(err, ErrMaxRequestsExceeded):
+		return "max_requests"
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// closedPortURL 返回一个没有服务监听的本地地址
+func closedPortURL(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return "http://" + addr
+}
+
+func TestClassifyConnRefused(t *testing.T) {
+	_, err := NewHTTPClient(closedPortURL(t)).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if !errors.Is(err, ErrConnRefused) {
+		t.Fatalf("err = %v，期望 ErrConnRefused", err)
+	}
+}
+
+func TestClassifyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := NewHTTPClient(server.URL).ExecuteRequestContext(ctx, RequestOptions{Method: "GET", Path: "/"})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v，期望 ErrTimeout", err)
+	}
+}
+
+func TestClassifyTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// 收到 ClientHello 后回复 handshake_failure 告警
+			conn.Read(make([]byte, 1024))
+			conn.Write([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x28})
+			conn.Close()
+		}
+	}()
+
+	_, err = NewHTTPClient("https://" + listener.Addr().String()).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if !errors.Is(err, ErrTLS) {
+		t.Fatalf("err = %v，期望 ErrTLS", err)
+	}
+}
+
+func TestClassifyDNS(t *testing.T) {
+	err := fmt.Errorf("请求失败: %w", &net.DNSError{Err: "no such host", Name: "nonexistent.invalid", IsNotFound: true})
+	if got := classifyError(err); got != ErrDNS {
+		t.Errorf("classifyError = %v，期望 ErrDNS", got)
+	}
+	if got := errorType(fmt.Errorf("%w: %w", ErrDNS, err)); got != "dns" {
+		t.Errorf("errorType = %q", got)
+	}
+}
+
+func TestResultErrorTypeForUnreachableTarget(t *testing.T) {
+	// 规则默认重试一次，重试前等待 2 秒
+	t.Parallel()
+	engine := NewEngine(mustLoadConfig(t, `
+name: unreachable
+rules:
+  r0:
+    method: GET
+    path: /
+`), closedPortURL(t))
+
+	if _, err := engine.Execute(); err == nil {
+		t.Fatal("目标不可达时期望返回错误")
+	}
+	if got := engine.Result().ErrorType; got != "conn_refused" {
+		t.Errorf("Result().ErrorType = %q，期望 conn_refused", got)
+	}
+}
@@ -1,10 +1,17 @@
 package sdk
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ExpressionEvaluator 表达式评估器
@@ -12,15 +19,108 @@ type ExpressionEvaluator struct {
 	response *Response
 	cookie   string
 	context  map[string]interface{} // 存储变量和提取的值
+	ruleResponses map[string]*Response // 各规则的响应，用于 r0.response.xxx 访问
+	funcs    map[string]func(args []string) (interface{}, error) // 通过 RegisterFunc 注册的自定义函数
+	oob      OOBProvider // 通过 SetOOBProvider 设置的带外交互服务
+	baseDir  string      // @file: 引用的相对目录，为空时使用当前工作目录
 }
 
 // NewExpressionEvaluator 创建表达式评估器
 func NewExpressionEvaluator() *ExpressionEvaluator {
 	return &ExpressionEvaluator{
-		context: make(map[string]interface{}),
+		context:       make(map[string]interface{}),
+		ruleResponses: make(map[string]*Response),
+		funcs:         make(map[string]func(args []string) (interface{}, error)),
 	}
 }
 
+// RegisterFunc 注册自定义表达式函数，表达式中的 name(arg1, arg2) 会在内置函数之后调用 fn
+// 参数先按普通取值求值（字面量、访问器、变量）再以字符串传入；返回布尔值的函数可以直接作为条件使用
+func (e *ExpressionEvaluator) RegisterFunc(name string, fn func(args []string) (interface{}, error)) {
+	e.funcs[name] = fn
+}
+
+// Reset 清空变量上下文和记录的规则响应
+func (e *ExpressionEvaluator) Reset() {
+	e.context = make(map[string]interface{})
+	e.ruleResponses = make(map[string]*Response)
+	e.response = nil
+	e.cookie = ""
+}
+
+// SetRuleResponse 记录规则的响应，之后可通过 r0.response.status 等形式访问
+func (e *ExpressionEvaluator) SetRuleResponse(ruleName string, response *Response) {
+	e.ruleResponses[ruleName] = response
+}
+
+// GetRuleResponse 获取规则的响应
+func (e *ExpressionEvaluator) GetRuleResponse(ruleName string) (*Response, bool) {
+	resp, ok := e.ruleResponses[ruleName]
+	return resp, ok
+}
+
+// ruleResponseRegex 匹配 r0.response.xxx 形式的规则响应访问
+var ruleResponseRegex = regexp.MustCompile(`^(\w+)\.(response\..+)$`)
+
+// useRuleResponse 将 r0.response.xxx 改写为 response.xxx 并临时切换到对应规则的响应
+// 返回改写后的表达式和用于恢复当前响应的函数
+func (e *ExpressionEvaluator) useRuleResponse(expr string) (string, func()) {
+	matches := ruleResponseRegex.FindStringSubmatch(expr)
+	if matches == nil {
+		return expr, func() {}
+	}
+
+	current := e.response
+	e.response = e.ruleResponses[matches[1]]
+	return matches[2], func() { e.response = current }
+}
+
+// SetBaseDir 设置表达式中 @file: 引用的相对目录，通常为 POC 文件所在目录
+func (e *ExpressionEvaluator) SetBaseDir(dir string) {
+	e.baseDir = dir
+}
+
+// fileDir 返回 @file: 引用的相对目录
+func (e *ExpressionEvaluator) fileDir() string {
+	if e.baseDir == "" {
+		return "."
+	}
+	return e.baseDir
+}
+
+// Extract 对响应求值取值表达式（如 response.headers.get('X-Token')、response.body.json('$.id')），
+// 用于规则的 extractors
+func (e *ExpressionEvaluator) Extract(expr string, response *Response, cookie string) (interface{}, error) {
+	e.response = response
+	e.cookie = cookie
+	return e.evaluateValue(removeComments(expr))
+}
+
+// SetVariable 设置上下文变量
+func (e *ExpressionEvaluator) SetVariable(name string, value interface{}) {
+	e.context[name] = value
+}
+
+// GetVariable 获取上下文变量
+func (e *ExpressionEvaluator) GetVariable(name string) (interface{}, bool) {
+	val, ok := e.context[name]
+	return val, ok
+}
+
+// deleteVariable 删除上下文变量
+func (e *ExpressionEvaluator) deleteVariable(name string) {
+	delete(e.context, name)
+}
+
+// Variables 获取所有上下文变量（字符串形式）
+func (e *ExpressionEvaluator) Variables() map[string]string {
+	vars := make(map[string]string, len(e.context))
+	for k, v := range e.context {
+		vars[k] = fmt.Sprintf("%v", v)
+	}
+	return vars
+}
+
 // Evaluate 评估表达式
 func (e *ExpressionEvaluator) Evaluate(expr string, response *Response, cookie string) (bool, error) {
 	e.response = response
@@ -30,17 +130,7 @@ func (e *ExpressionEvaluator) Evaluate(expr string, response *Response, cookie s
 	expr = removeComments(expr)
 	expr = strings.TrimSpace(expr)
 
-	// 处理逻辑运算符 && 和 ||
-	// 注意：&& 优先级高于 ||，需要先处理 ||
-	// 但为了简化，我们按出现顺序处理，复杂表达式建议使用括号
-	if strings.Contains(expr, "||") {
-		return e.evaluateOr(expr)
-	}
-	if strings.Contains(expr, "&&") {
-		return e.evaluateAnd(expr)
-	}
-
-	return e.evaluateSingle(expr)
+	return evaluateBoolean(expr, e.evaluateSingle)
 }
 
 func removeComments(s string) string {
@@ -51,53 +141,134 @@ func removeComments(s string) string {
 	return s
 }
 
-func (e *ExpressionEvaluator) evaluateAnd(expr string) (bool, error) {
-	parts := strings.Split(expr, "&&")
-	result := true
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		val, err := e.evaluateSingle(part)
-		if err != nil {
-			return false, err
+// evaluateBoolean 解析由 &&、|| 和括号组成的布尔表达式，&& 优先级高于 ||，从左到右短路求值
+// 不含逻辑运算符的项交给 term 求值；引号和函数调用括号内的运算符不参与拆分
+func evaluateBoolean(expr string, term func(string) (bool, error)) (bool, error) {
+	expr = strings.TrimSpace(expr)
+	if inner, ok := unwrapParens(expr); ok {
+		return evaluateBoolean(inner, term)
+	}
+
+	if parts := splitTopLevel(expr, "||"); len(parts) > 1 {
+		for _, part := range parts {
+			val, err := evaluateBoolean(part, term)
+			if err != nil {
+				return false, err
+			}
+			if val {
+				return true, nil
+			}
 		}
-		result = result && val
+		return false, nil
 	}
-	return result, nil
-}
 
-func (e *ExpressionEvaluator) evaluateOr(expr string) (bool, error) {
-	parts := strings.Split(expr, "||")
-	result := false
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		val, err := e.evaluateSingle(part)
-		if err != nil {
-			return false, err
+	if parts := splitTopLevel(expr, "&&"); len(parts) > 1 {
+		for _, part := range parts {
+			val, err := evaluateBoolean(part, term)
+			if err != nil {
+				return false, err
+			}
+			if !val {
+				return false, nil
+			}
 		}
-		result = result || val
-		if result {
-			break
+		return true, nil
+	}
+
+	return term(expr)
+}
+
+// unwrapParens 去掉包裹整个表达式的一对括号，(a) && (b) 这类不是整体包裹的不处理
+func unwrapParens(expr string) (string, bool) {
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return expr, false
+	}
+	var quote byte
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		ch := expr[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 && i != len(expr)-1 {
+				return expr, false
+			}
 		}
 	}
-	return result, nil
+	return expr[1 : len(expr)-1], true
 }
 
+
 func (e *ExpressionEvaluator) evaluateSingle(expr string) (bool, error) {
 	expr = strings.TrimSpace(expr)
 
-	// 处理括号表达式（简化处理）
-	if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
-		expr = strings.Trim(expr, "()")
-		return e.Evaluate(expr, e.response, e.cookie)
+	// 处理布尔字面量
+	switch expr {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	// 处理其他规则响应上的布尔函数（如 r0.response.body.contains('x')），比较表达式在取值时处理
+	if !hasComparison(expr) {
+		var restore func()
+		expr, restore = e.useRuleResponse(expr)
+		defer restore()
+	}
+
+	// 处理布尔访问器
+	if expr == "response.is_chunked" {
+		return e.isChunked(), nil
+	}
+	if expr == "response.insecure_redirect" {
+		return e.response != nil && e.response.InsecureRedirect != "", nil
+	}
+	if expr == oobReceivedExpr {
+		return e.evaluateOOBReceived()
+	}
+	if matches := partFuncRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluatePartFunc(matches[1], matches[2], matches[3], matches[4])
 	}
 
 	// 优先处理函数调用（返回布尔值的函数）
 	if strings.Contains(expr, "response.body.contains") {
 		return e.evaluateContains(expr)
 	}
+	if strings.Contains(expr, "response.body.not_contains") {
+		return e.evaluateNotContains(expr)
+	}
+	if strings.Contains(expr, "response.body.startswith") || strings.Contains(expr, "response.body.endswith") {
+		return e.evaluateAffix(expr)
+	}
 	if strings.Contains(expr, "cookie.contains") {
 		return e.evaluateCookieContains(expr)
 	}
+	if strings.Contains(expr, "response.headers.contains") {
+		return e.evaluateHeaderContains(expr)
+	}
+	if strings.Contains(expr, "response.headers.any_match") {
+		return e.evaluateHeaderAnyMatch(expr)
+	}
+	if strings.Contains(expr, "response.cookies.contains") {
+		return e.evaluateResponseCookiesContains(expr)
+	}
+	if matches := ipInCIDRRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluateIPInCIDR(matches[1], matches[2])
+	}
+	if matches := accessorContainsRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluateAccessorContains(matches[1], matches[2])
+	}
 
 	// 处理比较运算符: ==, !=, >=, <=, >, <
 	if strings.Contains(expr, "==") {
@@ -123,13 +294,20 @@ func (e *ExpressionEvaluator) evaluateSingle(expr string) (bool, error) {
 		return e.evaluateNumericComparison(expr, "<")
 	}
 
-	return false, fmt.Errorf("不支持的表达式: %s", expr)
+	// 返回布尔值的访问器（如 response.tls.expired）
+	if val, err := e.evaluateValue(expr); err == nil {
+		if b, ok := val.(bool); ok {
+			return b, nil
+		}
+	}
+
+	return false, fmt.Errorf("%w: %s", ErrUnsupportedExpression, expr)
 }
 
 func (e *ExpressionEvaluator) evaluateComparison(expr, op string, compare func(interface{}, interface{}) bool) (bool, error) {
 	parts := strings.Split(expr, op)
 	if len(parts) != 2 {
-		return false, fmt.Errorf("无效的比较表达式: %s", expr)
+		return false, fmt.Errorf("%w: %s", ErrInvalidComparison, expr)
 	}
 
 	left := strings.TrimSpace(parts[0])
@@ -151,39 +329,119 @@ func (e *ExpressionEvaluator) evaluateComparison(expr, op string, compare func(i
 func (e *ExpressionEvaluator) evaluateNumericComparison(expr, op string) (bool, error) {
 	parts := strings.Split(expr, op)
 	if len(parts) != 2 {
-		return false, fmt.Errorf("无效的数字比较表达式: %s", expr)
+		return false, fmt.Errorf("%w: %s", ErrInvalidComparison, expr)
 	}
 
 	left := strings.TrimSpace(parts[0])
 	right := strings.TrimSpace(parts[1])
 
-	leftVal, err := e.evaluateNumericValue(left)
-	if err != nil {
-		return false, err
+	leftVal, leftErr := e.evaluateNumericValue(left)
+	rightVal, rightErr := e.evaluateNumericValue(right)
+	if leftErr == nil && rightErr == nil {
+		return compareOrdered(compareInts(leftVal, rightVal), op)
 	}
 
-	rightVal, err := e.evaluateNumericValue(right)
-	if err != nil {
-		return false, err
+	// 非数字时尝试按时间比较（如 response.tls.not_after < now() + 30d）
+	if cmp, ok := e.compareTimeValues(left, right); ok {
+		return compareOrdered(cmp, op)
+	}
+
+	// 再尝试按版本号比较（如 nginx/1.9.0 < 1.18.0）
+	if cmp, ok := e.compareVersionValues(left, right); ok {
+		return compareOrdered(cmp, op)
 	}
 
+	if leftErr != nil {
+		return false, fmt.Errorf("%w: %s: %w", ErrInvalidComparison, expr, leftErr)
+	}
+	return false, fmt.Errorf("%w: %s: %w", ErrInvalidComparison, expr, rightErr)
+}
+
+// compareOrdered 根据比较结果（-1/0/1）和运算符得出布尔值
+func compareOrdered(cmp int, op string) (bool, error) {
 	switch op {
 	case ">=":
-		return leftVal >= rightVal, nil
+		return cmp >= 0, nil
 	case "<=":
-		return leftVal <= rightVal, nil
+		return cmp <= 0, nil
 	case ">":
-		return leftVal > rightVal, nil
+		return cmp > 0, nil
 	case "<":
-		return leftVal < rightVal, nil
+		return cmp < 0, nil
 	}
 
-	return false, fmt.Errorf("不支持的运算符: %s", op)
+	return false, fmt.Errorf("%w: 不支持的运算符 %s", ErrInvalidComparison, op)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// versionRegex 匹配字符串中的版本号，如 "nginx/1.18.0" 中的 1.18.0
+var versionRegex = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+func (e *ExpressionEvaluator) compareVersionValues(left, right string) (int, bool) {
+	leftVal, err := e.evaluateValue(left)
+	if err != nil {
+		return 0, false
+	}
+	rightVal, err := e.evaluateValue(right)
+	if err != nil {
+		return 0, false
+	}
+
+	leftVer := versionRegex.FindString(fmt.Sprintf("%v", leftVal))
+	rightVer := versionRegex.FindString(fmt.Sprintf("%v", rightVal))
+	if leftVer == "" || rightVer == "" {
+		return 0, false
+	}
+	return compareVersions(leftVer, rightVer), true
+}
+
+// compareVersions 逐段按数字比较版本号，缺失的段视为 0（1.9.0 < 1.18.0）
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if cmp := compareInts(av, bv); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
 }
 
 func (e *ExpressionEvaluator) evaluateValue(expr string) (interface{}, error) {
 	expr = strings.TrimSpace(expr)
 
+	// 处理 + 运算：两侧都是数字时相加，否则按字符串拼接
+	if parts := splitTopLevel(expr, "+"); len(parts) > 1 {
+		return e.evaluateConcat(parts)
+	}
+
+	// 处理自定义函数，先于内置访问器判断，参数中可以使用任意访问器
+	if matches := funcCallRegex.FindStringSubmatch(expr); matches != nil {
+		if fn, ok := e.funcs[matches[1]]; ok {
+			return e.callFunc(matches[1], fn, matches[2])
+		}
+	}
+
+	// 处理其他规则的响应（如 r0.response.status）
+	expr, restore := e.useRuleResponse(expr)
+	defer restore()
+
 	// 处理字符串字面量
 	if strings.HasPrefix(expr, "'") && strings.HasSuffix(expr, "'") {
 		return strings.Trim(expr, "'"), nil
@@ -192,6 +450,16 @@ func (e *ExpressionEvaluator) evaluateValue(expr string) (interface{}, error) {
 		return strings.Trim(expr, "\""), nil
 	}
 
+	// 处理 {{name}} 变量引用
+	if strings.HasPrefix(expr, "{{") && strings.HasSuffix(expr, "}}") {
+		return e.resolveVariableRef(expr)
+	}
+
+	// 当前时间，格式与证书时间相同
+	if expr == nowExpr {
+		return time.Now().UTC().Format(time.RFC3339), nil
+	}
+
 	// 处理 response.status
 	if expr == "response.status" {
 		if e.response == nil {
@@ -200,6 +468,124 @@ func (e *ExpressionEvaluator) evaluateValue(expr string) (interface{}, error) {
 		return e.response.Status, nil
 	}
 
+	// 处理 response.status_text
+	if expr == "response.status_text" {
+		if e.response == nil {
+			return "", nil
+		}
+		return e.response.StatusText, nil
+	}
+
+	// 处理 response.final_url（跟随重定向后最终请求的地址）
+	if expr == "response.final_url" {
+		if e.response == nil {
+			return "", nil
+		}
+		return e.response.URL, nil
+	}
+
+	// 处理 response.transfer_encoding 和 response.is_chunked
+	if expr == "response.transfer_encoding" {
+		if e.response == nil {
+			return "", nil
+		}
+		return strings.Join(e.response.TransferEncoding, ", "), nil
+	}
+	if expr == "response.is_chunked" {
+		return e.isChunked(), nil
+	}
+
+	// 处理 response.headers.count（响应头字段总数，同名的多个字段分别计数）
+	if expr == "response.headers.count" {
+		if e.response == nil {
+			return 0, nil
+		}
+		count := 0
+		for _, values := range e.response.Headers {
+			count += len(values)
+		}
+		return count, nil
+	}
+
+	// 处理 response.cookies.length（响应中 Set-Cookie 的数量）
+	if expr == "response.cookies.length" {
+		if e.response == nil {
+			return 0, nil
+		}
+		return len(e.response.Cookies), nil
+	}
+
+	// 处理 response.body（完整响应体，用于与文件内容等整体比较）
+	if expr == "response.body" {
+		if e.response == nil {
+			return "", nil
+		}
+		return e.response.Body, nil
+	}
+
+	// 处理 @file: 引用，读取相对于 POC 文件所在目录的文件内容
+	if strings.HasPrefix(expr, fileRefPrefix) {
+		return readFileRef(e.fileDir(), strings.TrimPrefix(expr, fileRefPrefix))
+	}
+
+	// 处理 response.body.length
+	if expr == "response.body.length" {
+		if e.response == nil {
+			return 0, nil
+		}
+		return len(e.response.Body), nil
+	}
+
+	// 处理 response.content_length（来自 Content-Length，HEAD 请求同样可用）
+	if expr == "response.content_length" {
+		if e.response == nil {
+			return -1, nil
+		}
+		return int(e.response.ContentLength), nil
+	}
+
+	// 处理 response.time（毫秒）
+	if expr == "response.time" {
+		if e.response == nil {
+			return 0, nil
+		}
+		return int(e.response.Duration.Milliseconds()), nil
+	}
+
+	// 处理 response.body.json('$.path') 和 response.body.json_array_length('$.path')
+	if matches := jsonPathRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluateJSONPath(matches[1], matches[2])
+	}
+
+	// 处理 response.cookie('name').httponly 等 Set-Cookie 属性
+	if matches := responseCookieRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluateResponseCookie(matches[1], matches[2])
+	}
+
+	// 处理 WWW-Authenticate 认证质询
+	if expr == "response.auth.scheme" || expr == "response.auth.realm" {
+		scheme, realm := e.authChallenge()
+		if expr == "response.auth.scheme" {
+			return scheme, nil
+		}
+		return realm, nil
+	}
+
+	// 处理 response.location（Location 按请求地址解析为绝对地址）
+	if expr == "response.location" {
+		return e.resolveLocation(), nil
+	}
+
+	// 处理 response.tls.subject 等证书访问器
+	if strings.HasPrefix(expr, "response.tls.") {
+		return e.evaluateTLSField(strings.TrimPrefix(expr, "response.tls."))
+	}
+
+	// 处理 response.body.count()
+	if strings.Contains(expr, "response.body.count") {
+		return e.evaluateBodyCount(expr)
+	}
+
 	// 处理 response.body.contains()
 	if strings.Contains(expr, "response.body.contains") {
 		return e.evaluateContains(expr)
@@ -210,32 +596,457 @@ func (e *ExpressionEvaluator) evaluateValue(expr string) (interface{}, error) {
 		return e.evaluateCookieContains(expr)
 	}
 
+	// 处理 response.trailers.get()
+	if matches := trailerGetRegex.FindStringSubmatch(expr); matches != nil {
+		if e.response == nil || e.response.Trailers == nil {
+			return "", nil
+		}
+		return e.response.Trailers.Get(matches[1]), nil
+	}
+
 	// 处理 response.headers.get()
 	if strings.Contains(expr, "response.headers.get") {
 		return e.evaluateHeaderGet(expr)
 	}
 
+	// 处理 cookie.get()
+	if strings.HasPrefix(expr, "cookie.get") {
+		return e.evaluateCookieGet(expr)
+	}
+
+	// 处理 response.body.extract('pattern')，返回首个匹配的第一个捕获组（没有捕获组时为整个匹配）
+	if matches := bodyExtractRegex.FindStringSubmatch(expr); matches != nil {
+		return e.evaluateExtract(matches[1])
+	}
+
+	// 处理 response.body.extract_count() 和 response.body.extract_all()
+	if strings.Contains(expr, "response.body.extract_count") || strings.Contains(expr, "response.body.extract_all") {
+		return e.evaluateExtractAll(expr)
+	}
+
 	// 处理数字
 	if num, err := strconv.Atoi(expr); err == nil {
 		return num, nil
 	}
 
+	// 处理上下文变量
+	if val, ok := e.context[expr]; ok {
+		return val, nil
+	}
+
 	return expr, nil
 }
 
+func (e *ExpressionEvaluator) evaluateConcat(parts []string) (interface{}, error) {
+	values := make([]interface{}, 0, len(parts))
+	numeric := true
+	for _, part := range parts {
+		val, err := e.evaluateValue(part)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := val.(int); !ok {
+			numeric = false
+		}
+		values = append(values, val)
+	}
+
+	if numeric {
+		sum := 0
+		for _, val := range values {
+			sum += val.(int)
+		}
+		return sum, nil
+	}
+
+	var sb strings.Builder
+	for _, val := range values {
+		sb.WriteString(fmt.Sprintf("%v", val))
+	}
+	return sb.String(), nil
+}
+
+// hasComparison 判断表达式是否包含（引号和括号外的）比较运算符
+func hasComparison(expr string) bool {
+	for _, op := range []string{"==", "!=", ">", "<"} {
+		if len(splitTopLevel(expr, op)) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTopLevel 按分隔符拆分表达式，忽略引号和括号内的分隔符
+func splitTopLevel(expr, sep string) []string {
+	var parts []string
+	var quote byte
+	depth := 0
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		ch := expr[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(expr[i:], sep):
+			parts = append(parts, expr[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
 func (e *ExpressionEvaluator) evaluateContains(expr string) (bool, error) {
-	// 解析 response.body.contains('text')
-	re := regexp.MustCompile(`response\.body\.contains\(['"]([^'"]+)['"]\)`)
+	// 解析 response.body.contains('text') 或 response.body.contains({{marker}})
+	re := regexp.MustCompile(`response\.body\.contains\((?:['"]([^'"]+)['"]|(\{\{[^}]+\}\}))\)`)
 	matches := re.FindStringSubmatch(expr)
-	if len(matches) != 2 {
-		return false, fmt.Errorf("无法解析 contains 表达式: %s", expr)
+	if len(matches) != 3 {
+		return false, fmt.Errorf("%w: 无法解析 contains 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	text, err := e.literalOrVariable(matches[1], matches[2])
+	if err != nil {
+		return false, err
 	}
 
 	if e.response == nil {
 		return false, nil
 	}
 
-	return strings.Contains(e.response.Body, matches[1]), nil
+	return e.response.BodyContains(text)
+}
+
+// jsonPathRegex 匹配 response.body.json('path') 和 response.body.json_array_length('path')
+var jsonPathRegex = regexp.MustCompile(`^response\.body\.json(_array_length)?\(['"]([^'"]+)['"]\)$`)
+
+// evaluateJSONPath 将响应体按 JSON 解析后按路径取值，arrayLength 时返回数组长度
+func (e *ExpressionEvaluator) evaluateJSONPath(arrayLength, path string) (interface{}, error) {
+	if e.response == nil {
+		return nil, fmt.Errorf("没有可用的响应")
+	}
+
+	data, err := e.response.decodedJSON()
+	if err != nil {
+		return nil, err
+	}
+	val, err := lookupJSONPath(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if arrayLength == "" {
+		return jsonValue(val), nil
+	}
+
+	arr, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON 路径 %s 不是数组", path)
+	}
+	return len(arr), nil
+}
+
+var funcCallRegex = regexp.MustCompile(`^(\w+)\((.*)\)$`)
+
+// callFunc 求值参数后调用自定义函数
+func (e *ExpressionEvaluator) callFunc(name string, fn func(args []string) (interface{}, error), argList string) (interface{}, error) {
+	var args []string
+	if strings.TrimSpace(argList) != "" {
+		for _, arg := range splitTopLevel(argList, ",") {
+			val, err := e.evaluateValue(arg)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fmt.Sprintf("%v", val))
+		}
+	}
+
+	result, err := fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("函数 %s 执行失败: %w", name, err)
+	}
+	return result, nil
+}
+
+var authRealmRegex = regexp.MustCompile(`(?i)\brealm\s*=\s*(?:"([^"]*)"|([^\s,]+))`)
+
+// authChallenge 解析第一个 WWW-Authenticate 质询的认证方案和 realm，没有该响应头时返回空
+func (e *ExpressionEvaluator) authChallenge() (scheme, realm string) {
+	if e.response == nil {
+		return "", ""
+	}
+	header := strings.TrimSpace(e.response.Headers.Get("WWW-Authenticate"))
+	if header == "" {
+		return "", ""
+	}
+
+	scheme, params, _ := strings.Cut(header, " ")
+	if m := authRealmRegex.FindStringSubmatch(params); m != nil {
+		realm = m[1] + m[2]
+	}
+	return scheme, realm
+}
+
+// resolveLocation 将 Location 响应头相对于请求地址解析为绝对地址，解析失败时返回原值
+func (e *ExpressionEvaluator) resolveLocation() string {
+	if e.response == nil {
+		return ""
+	}
+	location := e.response.Headers.Get("Location")
+	if location == "" {
+		return ""
+	}
+
+	ref, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	base, err := url.Parse(e.response.URL)
+	if err != nil || e.response.URL == "" {
+		return location
+	}
+	return base.ResolveReference(ref).String()
+}
+
+var trailerGetRegex = regexp.MustCompile(`^response\.trailers\.get\(['"]([^'"]+)['"]\)$`)
+
+var responseCookieRegex = regexp.MustCompile(`^response\.cookie\(['"]([^'"]+)['"]\)(?:\.(\w+))?$`)
+
+// evaluateResponseCookie 读取响应中指定 Set-Cookie 的属性，未指定属性时返回其值
+// Cookie 不存在时字符串属性为空、布尔属性为 false
+func (e *ExpressionEvaluator) evaluateResponseCookie(name, field string) (interface{}, error) {
+	var cookie *http.Cookie
+	if e.response != nil {
+		for _, c := range e.response.Cookies {
+			if c.Name == name {
+				cookie = c
+				break
+			}
+		}
+	}
+
+	switch field {
+	case "", "value", "path", "domain", "samesite":
+		if cookie == nil {
+			return "", nil
+		}
+	case "exists", "httponly", "secure":
+		if cookie == nil {
+			return false, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的 Cookie 属性 %s", ErrUnsupportedExpression, field)
+	}
+
+	switch field {
+	case "exists":
+		return true, nil
+	case "httponly":
+		return cookie.HttpOnly, nil
+	case "secure":
+		return cookie.Secure, nil
+	case "path":
+		return cookie.Path, nil
+	case "domain":
+		return cookie.Domain, nil
+	case "samesite":
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			return "lax", nil
+		case http.SameSiteStrictMode:
+			return "strict", nil
+		case http.SameSiteNoneMode:
+			return "none", nil
+		}
+		return "", nil
+	default:
+		return cookie.Value, nil
+	}
+}
+
+// evaluateTLSField 读取协商的协议版本、加密套件或对端证书（证书链中的第一个）的字段，非 HTTPS 响应时返回空值
+func (e *ExpressionEvaluator) evaluateTLSField(field string) (interface{}, error) {
+	// 协商的协议版本和加密套件，如 "TLS 1.2"、"TLS_RSA_WITH_AES_128_CBC_SHA"
+	if field == "version" || field == "cipher" {
+		if e.response == nil || e.response.TLS == nil {
+			return "", nil
+		}
+		if field == "version" {
+			return tls.VersionName(e.response.TLS.Version), nil
+		}
+		return tls.CipherSuiteName(e.response.TLS.CipherSuite), nil
+	}
+
+	var cert *x509.Certificate
+	if e.response != nil && e.response.TLS != nil && len(e.response.TLS.PeerCertificates) > 0 {
+		cert = e.response.TLS.PeerCertificates[0]
+	}
+
+	switch field {
+	case "subject", "issuer", "not_after", "not_before", "dns_names":
+		if cert == nil {
+			return "", nil
+		}
+	case "expired", "self_signed":
+		if cert == nil {
+			return false, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的证书字段 response.tls.%s", ErrUnsupportedExpression, field)
+	}
+
+	switch field {
+	case "subject":
+		return cert.Subject.String(), nil
+	case "issuer":
+		return cert.Issuer.String(), nil
+	case "not_after":
+		return cert.NotAfter.UTC().Format(time.RFC3339), nil
+	case "not_before":
+		return cert.NotBefore.UTC().Format(time.RFC3339), nil
+	case "dns_names":
+		return strings.Join(cert.DNSNames, ", "), nil
+	case "expired":
+		return time.Now().After(cert.NotAfter), nil
+	default: // self_signed
+		return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil, nil
+	}
+}
+
+// ipInCIDRRegex 匹配 ip_in_cidr(value, 'cidr')，value 可以是字面量、访问器或变量
+var ipInCIDRRegex = regexp.MustCompile(`^ip_in_cidr\((.+),\s*['"]([^'"]+)['"]\)$`)
+
+// evaluateIPInCIDR 判断取值中的 IP 是否在网段内，IP 无法解析时返回 false
+// 取值为逗号分隔的列表（如 X-Forwarded-For）时使用第一个地址，允许带端口
+func (e *ExpressionEvaluator) evaluateIPInCIDR(valueExpr, cidr string) (bool, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false, fmt.Errorf("无效的网段: %s", cidr)
+	}
+
+	value, err := e.evaluateValue(strings.TrimSpace(valueExpr))
+	if err != nil {
+		return false, err
+	}
+
+	host := strings.TrimSpace(strings.Split(fmt.Sprintf("%v", value), ",")[0])
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return false, nil
+	}
+	return network.Contains(ip), nil
+}
+
+// accessorContainsRegex 匹配 <访问器>.contains('text') 形式的通用包含判断
+var accessorContainsRegex = regexp.MustCompile(`^(.+)\.contains\(['"]([^'"]+)['"]\)$`)
+
+func (e *ExpressionEvaluator) evaluateAccessorContains(accessor, text string) (bool, error) {
+	// 解析 response.status_text.contains('text') 等字符串访问器上的 contains
+	val, err := e.evaluateValue(accessor)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(fmt.Sprintf("%v", val), text), nil
+}
+
+// isChunked 判断响应是否使用分块传输编码
+func (e *ExpressionEvaluator) isChunked() bool {
+	if e.response == nil {
+		return false
+	}
+	for _, te := range e.response.TransferEncoding {
+		if strings.EqualFold(te, "chunked") {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ExpressionEvaluator) evaluateNotContains(expr string) (bool, error) {
+	// 解析 response.body.not_contains('text') 或 response.body.not_contains({{marker}})
+	re := regexp.MustCompile(`response\.body\.not_contains\((?:['"]([^'"]+)['"]|(\{\{[^}]+\}\}))\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 3 {
+		return false, fmt.Errorf("%w: 无法解析 not_contains 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	text, err := e.literalOrVariable(matches[1], matches[2])
+	if err != nil {
+		return false, err
+	}
+
+	if e.response == nil {
+		return true, nil
+	}
+
+	found, err := e.response.BodyContains(text)
+	return !found, err
+}
+
+// literalOrVariable 返回字面量参数，或解析 {{name}} 形式的变量引用
+func (e *ExpressionEvaluator) literalOrVariable(literal, ref string) (string, error) {
+	if ref == "" {
+		return literal, nil
+	}
+	return e.resolveVariableRef(ref)
+}
+
+// resolveVariableRef 解析 {{name}} 形式的变量引用，变量未定义时报错
+func (e *ExpressionEvaluator) resolveVariableRef(ref string) (string, error) {
+	matches := templateRegex.FindStringSubmatch(ref)
+	if matches == nil {
+		return "", fmt.Errorf("%w: 无效的变量引用 %s", ErrUnsupportedExpression, ref)
+	}
+	val, ok := e.context[matches[1]]
+	if !ok {
+		return "", fmt.Errorf("变量 %s 未定义", matches[1])
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+func (e *ExpressionEvaluator) evaluateAffix(expr string) (bool, error) {
+	// 解析 response.body.startswith('text') / response.body.endswith('text')，参数可以为空字符串
+	re := regexp.MustCompile(`response\.body\.(startswith|endswith)\(['"]([^'"]*)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 3 {
+		return false, fmt.Errorf("%w: 无法解析 startswith/endswith 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	body := ""
+	if e.response != nil {
+		body = e.response.Body
+	}
+
+	if matches[1] == "startswith" {
+		return strings.HasPrefix(body, matches[2]), nil
+	}
+	return strings.HasSuffix(body, matches[2]), nil
+}
+
+func (e *ExpressionEvaluator) evaluateBodyCount(expr string) (int, error) {
+	// 解析 response.body.count('text')
+	re := regexp.MustCompile(`response\.body\.count\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return 0, fmt.Errorf("%w: 无法解析 body.count 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	if e.response == nil {
+		return 0, nil
+	}
+
+	return strings.Count(e.response.Body, matches[1]), nil
 }
 
 func (e *ExpressionEvaluator) evaluateCookieContains(expr string) (bool, error) {
@@ -243,37 +1054,149 @@ func (e *ExpressionEvaluator) evaluateCookieContains(expr string) (bool, error)
 	re := regexp.MustCompile(`cookie\.contains\(['"]([^'"]+)['"]\)`)
 	matches := re.FindStringSubmatch(expr)
 	if len(matches) != 2 {
-		return false, fmt.Errorf("无法解析 cookie.contains 表达式: %s", expr)
+		return false, fmt.Errorf("%w: 无法解析 cookie.contains 表达式: %s", ErrUnsupportedExpression, expr)
 	}
 
 	return strings.Contains(e.cookie, matches[1]), nil
 }
 
+func (e *ExpressionEvaluator) evaluateCookieGet(expr string) (string, error) {
+	// 解析 cookie.get('name')，按 Cookie 头格式（name=value; name2=value2）查找
+	re := regexp.MustCompile(`cookie\.get\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("%w: 无法解析 cookie.get 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	for _, part := range strings.Split(e.cookie, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && name == matches[1] {
+			return strings.Trim(value, "\""), nil
+		}
+	}
+	return "", nil
+}
+
+func (e *ExpressionEvaluator) evaluateHeaderContains(expr string) (bool, error) {
+	// 解析 response.headers.contains('header-name')，仅判断响应头是否存在（值可以为空）
+	re := regexp.MustCompile(`response\.headers\.contains\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return false, fmt.Errorf("%w: 无法解析 headers.contains 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	if e.response == nil {
+		return false, nil
+	}
+
+	return e.response.Headers.Values(matches[1]) != nil, nil
+}
+
+func (e *ExpressionEvaluator) evaluateResponseCookiesContains(expr string) (bool, error) {
+	// 解析 response.cookies.contains('name')，判断响应是否设置了指定名称的 Cookie
+	re := regexp.MustCompile(`response\.cookies\.contains\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return false, fmt.Errorf("%w: 无法解析 cookies.contains 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	if e.response == nil {
+		return false, nil
+	}
+
+	for _, cookie := range e.response.Cookies {
+		if cookie.Name == matches[1] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *ExpressionEvaluator) evaluateHeaderAnyMatch(expr string) (bool, error) {
+	// 解析 response.headers.any_match('regex')，逐行匹配 "Name: value"
+	re := regexp.MustCompile(`response\.headers\.any_match\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 2 {
+		return false, fmt.Errorf("%w: 无法解析 headers.any_match 表达式: %s", ErrUnsupportedExpression, expr)
+	}
+
+	regex, err := regexp.Compile(convertRustRegex(matches[1]))
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrBadRegex, err)
+	}
+
+	if e.response == nil {
+		return false, nil
+	}
+
+	for _, line := range headerLines(e.response.Headers) {
+		if regex.MatchString(line) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (e *ExpressionEvaluator) evaluateHeaderGet(expr string) (string, error) {
 	// 解析 response.headers.get('header-name')
 	re := regexp.MustCompile(`response\.headers\.get\(['"]([^'"]+)['"]\)`)
 	matches := re.FindStringSubmatch(expr)
 	if len(matches) != 2 {
-		return "", fmt.Errorf("无法解析 headers.get 表达式: %s", expr)
+		return "", fmt.Errorf("%w: 无法解析 headers.get 表达式: %s", ErrUnsupportedExpression, expr)
 	}
 
 	if e.response == nil {
 		return "", nil
 	}
 
-	headerName := matches[1]
-	headerNameLower := strings.ToLower(headerName)
-	
 	// 查找响应头（不区分大小写）
-	for k, v := range e.response.Headers {
-		if strings.ToLower(k) == headerNameLower {
-			if len(v) > 0 {
-				return v[0], nil
-			}
-		}
+	return e.response.Headers.Get(matches[1]), nil
+}
+
+func (e *ExpressionEvaluator) evaluateExtractAll(expr string) (interface{}, error) {
+	// 解析 response.body.extract_count('pattern') / response.body.extract_all('pattern')
+	re := regexp.MustCompile(`response\.body\.(extract_count|extract_all)\(['"]([^'"]+)['"]\)`)
+	matches := re.FindStringSubmatch(expr)
+	if len(matches) != 3 {
+		return nil, fmt.Errorf("%w: 无法解析 body.extract_all 表达式: %s", ErrUnsupportedExpression, expr)
 	}
 
-	return "", nil
+	body := ""
+	if e.response != nil {
+		body = e.response.Body
+	}
+
+	values, err := extractAll(matches[2], body)
+	if err != nil {
+		return nil, err
+	}
+
+	if matches[1] == "extract_count" {
+		return len(values), nil
+	}
+	return strings.Join(values, "; "), nil
+}
+
+// bodyExtractRegex 匹配 response.body.extract('pattern')
+var bodyExtractRegex = regexp.MustCompile(`^response\.body\.extract\(['"](.+)['"]\)$`)
+
+// evaluateExtract 返回响应体中首个匹配的第一个捕获组，没有捕获组时返回整个匹配，未匹配时返回空字符串
+func (e *ExpressionEvaluator) evaluateExtract(pattern string) (interface{}, error) {
+	regex, err := regexp.Compile(convertRustRegex(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadRegex, err)
+	}
+	if e.response == nil {
+		return "", nil
+	}
+	match := regex.FindStringSubmatch(e.response.Body)
+	switch {
+	case match == nil:
+		return "", nil
+	case len(match) > 1:
+		return match[1], nil
+	}
+	return match[0], nil
 }
 
 func (e *ExpressionEvaluator) evaluateNumericValue(expr string) (int, error) {
@@ -0,0 +1,633 @@
+package sdk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// evalExpr 在给定响应上求值表达式
+func evalExpr(t *testing.T, e *ExpressionEvaluator, expr string, response *Response) bool {
+	t.Helper()
+	ok, err := e.Evaluate(expr, response, "")
+	if err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+	return ok
+}
+
+func TestRegisterFuncWithAccessorArgument(t *testing.T) {
+	e := NewExpressionEvaluator()
+	e.RegisterFunc("reverse", func(args []string) (interface{}, error) {
+		r := []rune(args[0])
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	})
+
+	headers := make(http.Header)
+	headers.Set("X-Id", "abc")
+	response := &Response{Status: 200, Headers: headers, Body: "xyz"}
+
+	for _, expr := range []string{
+		"reverse(response.headers.get('X-Id')) == 'cba'",
+		"reverse(response.body) == 'zyx'",
+		"reverse(response.status) == '002'",
+	} {
+		if !evalExpr(t, e, expr, response) {
+			t.Errorf("%s 期望为 true", expr)
+		}
+	}
+}
+
+func TestExtractAllAndCount(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header),
+		Body: `<li>user=alice</li><li>user=bob</li><li>user=carol</li>`}
+	e := NewExpressionEvaluator()
+
+	for _, expr := range []string{
+		"response.body.extract_count('user=(\\w+)') == 3",
+		"response.body.extract_count('user=(\\w+)') > 2",
+		"response.body.extract_count('admin=(\\w+)') == 0",
+		"response.body.extract_all('user=(\\w+)') == 'alice; bob; carol'",
+	} {
+		if !evalExpr(t, e, expr, response) {
+			t.Errorf("%s 期望为 true", expr)
+		}
+	}
+}
+
+func TestHeaderLookupNonCanonicalNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 直接写入 map，按原样的小写名称发送
+		w.Header()["x-powered-by"] = []string{"PHP/7.4"}
+		w.Header()["set-cookie"] = []string{"sid=1"}
+	}))
+	defer server.Close()
+
+	response, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExpressionEvaluator()
+	for _, expr := range []string{
+		"response.headers.get('X-POWERED-BY') == 'PHP/7.4'",
+		"response.headers.get('x-powered-by').contains('PHP')",
+	} {
+		if !evalExpr(t, e, expr, response) {
+			t.Errorf("%s 期望为 true", expr)
+		}
+	}
+
+	cookie, err := NewCookieExtractor().ExtractCookie("response.headers.get('SET-COOKIE')", response)
+	if err != nil || cookie != "sid=1" {
+		t.Errorf("ExtractCookie = %q, %v", cookie, err)
+	}
+}
+
+func TestHeadersContains(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("X-Powered-By", "Express")
+	headers["X-Empty"] = []string{""}
+	response := &Response{Status: 200, Headers: headers}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.headers.contains('x-powered-by')": true,
+		"response.headers.contains('X-EMPTY')":      true,
+		"response.headers.contains('X-Missing')":    false,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestCompareNumericAccessors(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header), Body: strings.Repeat("a", 500), Duration: 20 * time.Millisecond}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.body.length > response.status":            true,
+		"response.status >= response.body.length":           false,
+		"response.body.count('a') == response.body.length":  true,
+		"response.time < response.status":                   true,
+		"response.body.count('aa') <= response.body.length": true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestBodyNotContains(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header), Body: "Welcome, admin"}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.body.not_contains('Access Denied')":                            true,
+		"response.body.not_contains('admin')":                                    false,
+		"response.status == 200 && response.body.not_contains('Access Denied')":  true,
+		"response.body.contains('admin') && response.body.not_contains('admin')": false,
+		"response.body.not_contains('admin') || response.status == 200":          true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("X-Id", "42")
+	response := &Response{Status: 200, Headers: headers, Body: "/users/42-suffix"}
+	e := NewExpressionEvaluator()
+	e.SetVariable("prefix", "/users/")
+
+	for expr, want := range map[string]bool{
+		"response.headers.get('X-Id') + '-suffix' == '42-suffix'":  true,
+		"{{prefix}} + response.headers.get('X-Id') == '/users/42'": true,
+		"response.body.contains('users')":                          true,
+		"'a' + 'b' + 'c' == 'abc'":                                 true,
+		"response.status + 1 == 201":                               true,
+		"response.headers.get('X-Id') + 1 == '421'":                true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestCookieGet(t *testing.T) {
+	response := &Response{Status: 200, Headers: make(http.Header)}
+	e := NewExpressionEvaluator()
+	cookie := "session=abc; role=admin; theme="
+
+	for expr, want := range map[string]bool{
+		"cookie.get('role') == 'admin'":  true,
+		"cookie.get('session') == 'abc'": true,
+		"cookie.get('theme') == ''":      true,
+		"cookie.get('missing') == ''":    true,
+		"cookie.get('role') == 'user'":   false,
+	} {
+		got, err := e.Evaluate(expr, response, cookie)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestBodyStartsWithEndsWith(t *testing.T) {
+	e := NewExpressionEvaluator()
+	response := &Response{Status: 200, Headers: make(http.Header), Body: "<br />\n<b>Warning</b>: include(): failed</html>"}
+
+	for expr, want := range map[string]bool{
+		"response.body.startswith('<br />')": true,
+		"response.body.endswith('</html>')":  true,
+		"response.body.startswith('<html>')": false,
+		"response.body.endswith('<br />')":   false,
+		"response.body.startswith('')":       true,
+		"response.body.endswith('')":         true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	empty := &Response{Status: 200, Headers: make(http.Header)}
+	if !evalExpr(t, e, "response.body.startswith('')", empty) || evalExpr(t, e, "response.body.endswith('x')", empty) {
+		t.Error("空响应体的 startswith/endswith 结果不正确")
+	}
+}
+
+func TestResponseCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/one":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1"})
+		case "/many":
+			for _, name := range []string{"a", "b", "c"} {
+				http.SetCookie(w, &http.Cookie{Name: name, Value: "1"})
+			}
+		}
+	}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+	e := NewExpressionEvaluator()
+
+	tests := []struct {
+		path string
+		expr string
+		want bool
+	}{
+		{"/none", "response.cookies.length == 0", true},
+		{"/none", "response.cookies.contains('sid')", false},
+		{"/one", "response.cookies.length == 1", true},
+		{"/one", "response.cookies.contains('sid')", true},
+		{"/many", "response.cookies.length >= 3", true},
+		{"/many", "response.cookies.contains('b')", true},
+		{"/many", "response.cookies.contains('sid')", false},
+	}
+	for _, tt := range tests {
+		response, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: tt.path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := evalExpr(t, e, tt.expr, response); got != tt.want {
+			t.Errorf("%s: %s = %v，期望 %v", tt.path, tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestHeadersCountAndAnyMatch(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx")
+	headers.Set("X-Debug-Token", "abc123")
+	headers.Add("Set-Cookie", "a=1")
+	headers.Add("Set-Cookie", "b=2")
+	response := &Response{Status: 200, Headers: headers}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.headers.count == 4":                        true,
+		"response.headers.count > 10":                        false,
+		"response.headers.any_match('(?i)^x-debug-token: ')": true,
+		"response.headers.any_match('^Server: nginx$')":      true,
+		"response.headers.any_match('X-Powered-By')":         false,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx/1.9.0")
+	response := &Response{Status: 200, Headers: headers}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"'1.9.0' < '1.18.0'":                        true,
+		"'1.18.0' > '1.9.0'":                        true,
+		"'1.18.0' <= '1.18'":                        true,
+		"'2.0' < '1.18.0'":                          false,
+		"response.headers.get('Server') < '1.18.0'": true,
+		"response.headers.get('Server') >= '1.9'":   true,
+		"response.headers.get('Server') > '1.10.3'": false,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if _, err := e.Evaluate("response.headers.get('Server') < 'latest'", response, ""); err == nil {
+		t.Error("不含版本号的字符串比较应返回错误")
+	}
+}
+
+func TestIPInCIDR(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("X-Forwarded-For", "10.1.2.3, 192.168.0.1")
+	headers.Set("X-Real-IP", "[fe80::1]:8080")
+	headers.Set("X-Bad-IP", "not-an-ip")
+	response := &Response{Status: 200, Headers: headers}
+	e := NewExpressionEvaluator()
+	e.SetVariable("origin_ip", "169.254.169.254")
+
+	for expr, want := range map[string]bool{
+		"ip_in_cidr('10.0.0.1', '10.0.0.0/8')":                                  true,
+		"ip_in_cidr('11.0.0.1', '10.0.0.0/8')":                                  false,
+		"ip_in_cidr(response.headers.get('X-Forwarded-For'), '10.0.0.0/8')":     true,
+		"ip_in_cidr(response.headers.get('X-Forwarded-For'), '192.168.0.0/16')": false,
+		"ip_in_cidr(response.headers.get('X-Real-IP'), 'fe80::/10')":            true,
+		"ip_in_cidr(origin_ip, '169.254.0.0/16')":                               true,
+		"ip_in_cidr(response.headers.get('X-Bad-IP'), '0.0.0.0/0')":             false,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if _, err := e.Evaluate("ip_in_cidr('10.0.0.1', '10.0.0.0/33')", response, ""); err == nil {
+		t.Error("无效网段应返回错误")
+	}
+}
+
+func TestLogicalPrecedence(t *testing.T) {
+	response := &Response{Status: 302, Body: "welcome admin && guest || root"}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.status == 200 && response.body.contains('x') || response.status == 302":        true,
+		"response.status == 302 || response.status == 200 && response.body.contains('x')":        true,
+		"(response.status == 200 || response.status == 302) && response.body.contains('admin')":  true,
+		"(response.status == 200 || response.status == 302) && response.body.contains('nobody')": false,
+		"response.body.contains('admin && guest') && response.body.contains('|| root')":          true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestJSONArrayLength(t *testing.T) {
+	response := &Response{Status: 200, Body: `{"empty":[],"one":["a"],"data":[1,2,3,4,5,6,7,8,9,10,11,12],"user":{"name":"x"},"name":"x"}`}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.body.json_array_length('$.empty') == 0": true,
+		"response.body.json_array_length('$.one') == 1":   true,
+		"response.body.json_array_length('$.data') > 10":  true,
+		"response.body.json_array_length('$.data') > 12":  false,
+		"response.body.json('$.data[11]') == 12":          true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	for _, path := range []string{"$.user", "$.name", "$.missing"} {
+		expr := "response.body.json_array_length('" + path + "') > 0"
+		if _, err := e.Evaluate(expr, response, ""); err == nil {
+			t.Errorf("%s 应返回错误", expr)
+		}
+	}
+}
+
+func TestTLSCertificateFields(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cert := server.Certificate()
+
+	response, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.tls.subject.contains('Acme Co')":                                     true,
+		"response.tls.subject.contains('admin')":                                       false,
+		"response.tls.issuer == '" + cert.Issuer.String() + "'":                        true,
+		"response.tls.not_after == '" + cert.NotAfter.UTC().Format(time.RFC3339) + "'": true,
+		"response.tls.dns_names.contains('example.com')":                               true,
+		"response.tls.self_signed":                                                     true,
+		"response.tls.expired":                                                         false,
+		"response.tls.expired || response.tls.self_signed":                             true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	plain := &Response{Status: 200}
+	for _, expr := range []string{"response.tls.subject == ''", "response.tls.self_signed == false"} {
+		if !evalExpr(t, e, expr, plain) {
+			t.Errorf("HTTP 响应: %s 应为 true", expr)
+		}
+	}
+	if _, err := e.Evaluate("response.tls.serial == '1'", response, ""); err == nil {
+		t.Error("不支持的证书字段应返回错误")
+	}
+}
+
+func TestResponseCookieAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		http.SetCookie(w, &http.Cookie{Name: "secure_sid", Value: "xyz", Path: "/app", Domain: "example.com",
+			HttpOnly: true, Secure: true, SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode})
+	}))
+	defer server.Close()
+
+	response, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.cookie('session') == 'abc'":                                                        true,
+		"response.cookie('session').httponly == false":                                               true,
+		"response.cookie('session').secure":                                                          false,
+		"response.cookie('session').samesite == ''":                                                  true,
+		"response.cookie('secure_sid').value == 'xyz'":                                               true,
+		"response.cookie('secure_sid').httponly":                                                     true,
+		"response.cookie('secure_sid').secure && response.cookie('secure_sid').samesite == 'strict'": true,
+		"response.cookie('secure_sid').path == '/app'":                                               true,
+		"response.cookie('secure_sid').domain == 'example.com'":                                      true,
+		"response.cookie('tracking').samesite == 'none'":                                             true,
+		"response.cookie('missing').exists":                                                          false,
+		"response.cookie('missing').httponly":                                                        false,
+		"response.cookie('session').exists":                                                          true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if _, err := e.Evaluate("response.cookie('session').maxage == 0", response, ""); err == nil {
+		t.Error("不支持的 Cookie 属性应返回错误")
+	}
+}
+
+func TestAuthChallenge(t *testing.T) {
+	e := NewExpressionEvaluator()
+	tests := []struct {
+		header string
+		scheme string
+		realm  string
+	}{
+		{`Basic realm="Router Admin", charset="UTF-8"`, "Basic", "Router Admin"},
+		{`Bearer realm=api, error="invalid_token"`, "Bearer", "api"},
+		{`Bearer error="invalid_token"`, "Bearer", ""},
+		{``, "", ""},
+	}
+	for _, tt := range tests {
+		headers := make(http.Header)
+		if tt.header != "" {
+			headers.Set("WWW-Authenticate", tt.header)
+		}
+		response := &Response{Status: 401, Headers: headers}
+
+		expr := "response.auth.scheme == '" + tt.scheme + "' && response.auth.realm == '" + tt.realm + "'"
+		if !evalExpr(t, e, expr, response) {
+			t.Errorf("WWW-Authenticate %q: 期望 scheme %q, realm %q", tt.header, tt.scheme, tt.realm)
+		}
+	}
+
+	headers := make(http.Header)
+	headers.Set("WWW-Authenticate", `Basic realm="Router Admin"`)
+	if !evalExpr(t, e, "response.auth.realm.contains('Router')", &Response{Status: 401, Headers: headers}) {
+		t.Error("response.auth.realm.contains('Router') 应为 true")
+	}
+}
+
+func TestContainsVariableReference(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("X-Echo", "gp1234")
+	response := &Response{Status: 200, Headers: headers, Body: "hello gp1234"}
+	e := NewExpressionEvaluator()
+	e.SetVariable("marker", "gp1234")
+	e.SetVariable("other", "gp9999")
+
+	for expr, want := range map[string]bool{
+		"response.body.contains({{marker}})":           true,
+		"response.body.contains({{other}})":            false,
+		"response.body.not_contains({{other}})":        true,
+		"response.headers.get('X-Echo') == {{marker}}": true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if _, err := e.Evaluate("response.body.contains({{missing}})", response, ""); err == nil {
+		t.Error("未定义的变量应返回错误")
+	}
+}
+
+func TestResponseLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if location := r.URL.Query().Get("to"); location != "" {
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+	e := NewExpressionEvaluator()
+
+	for to, want := range map[string]string{
+		"../login":                 server.URL + "/login",
+		"next":                     server.URL + "/app/next",
+		"/admin?x=1":               server.URL + "/admin?x=1",
+		"https://evil.com/landing": "https://evil.com/landing",
+		"//cdn.example.com/a":      "http://cdn.example.com/a",
+		"":                         "",
+	} {
+		resp, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/app/page?to=" + url.QueryEscape(to), MaxRedirects: intPtr(0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !evalExpr(t, e, "response.location == '"+want+"'", resp) {
+			t.Errorf("Location %q: response.location 期望 %q", to, want)
+		}
+	}
+}
+
+func TestExpressionErrorSentinels(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx")
+	response := &Response{Status: 200, Headers: headers, Body: "hello"}
+	e := NewExpressionEvaluator()
+
+	tests := []struct {
+		expr string
+		want error
+	}{
+		{"response.body.frobnicate('x')", ErrUnsupportedExpression},
+		{"response.cookie('sid').maxage == 0", ErrUnsupportedExpression},
+		{"response.tls.serial == '1'", ErrUnsupportedExpression},
+		{"response.body > 3", ErrInvalidComparison},
+		{"response.headers.get('Server') < 'latest'", ErrInvalidComparison},
+		{"response.headers.any_match('[a-')", ErrBadRegex},
+		{"response.body.extract('[a-') == ''", ErrBadRegex},
+	}
+	for _, tt := range tests {
+		if _, err := e.Evaluate(tt.expr, response, ""); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v，期望 %v", tt.expr, err, tt.want)
+		}
+	}
+
+	// 变量未定义属于运行时错误，不归入表达式写法错误
+	_, err := e.Evaluate("response.body.contains({{missing}})", response, "")
+	if err == nil || errors.Is(err, ErrUnsupportedExpression) || errors.Is(err, ErrInvalidComparison) || errors.Is(err, ErrBadRegex) {
+		t.Errorf("未定义变量的 err = %v", err)
+	}
+}
+
+func TestResponseTrailers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		w.Write([]byte("payload"))
+		w.Header().Set("Grpc-Status", "7")
+		w.Header().Set("Grpc-Message", "permission denied")
+	}))
+	defer server.Close()
+
+	response, err := NewHTTPClient(server.URL).ExecuteRequest(RequestOptions{Method: "POST", Path: "/svc.Admin/List"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Body != "payload" {
+		t.Errorf("Body = %q", response.Body)
+	}
+	e := NewExpressionEvaluator()
+
+	for expr, want := range map[string]bool{
+		"response.trailers.get('grpc-status') == '7'":              true,
+		"response.trailers.get('Grpc-Message').contains('denied')": true,
+		"response.trailers.get('grpc-status') == '0'":              false,
+		"response.trailers.get('X-Missing') == ''":                 true,
+		"response.headers.get('Grpc-Status') == ''":                true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if !evalExpr(t, e, "response.trailers.get('grpc-status') == ''", &Response{Status: 200}) {
+		t.Error("没有 trailer 的响应应返回空字符串")
+	}
+}
+
+func TestResponseFinalURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			http.Redirect(w, r, "/login?next=%2Fadmin", http.StatusFound)
+		default:
+			w.Write([]byte("page"))
+		}
+	}))
+	defer server.Close()
+
+	e := NewExpressionEvaluator()
+	client := NewHTTPClient(server.URL)
+	redirected, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct, err := client.ExecuteRequest(RequestOptions{Method: "GET", Path: "/home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		response *Response
+		expr     string
+		want     bool
+	}{
+		{redirected, "response.final_url.contains('/login')", true},
+		{redirected, "response.final_url == '" + server.URL + "/login?next=%2Fadmin'", true},
+		{redirected, "response.final_url.contains('/admin?')", false},
+		{direct, "response.final_url.contains('/login')", false},
+		{direct, "response.final_url == '" + server.URL + "/home'", true},
+	} {
+		if got := evalExpr(t, e, c.expr, c.response); got != c.want {
+			t.Errorf("%s = %v，期望 %v（URL = %s）", c.expr, got, c.want, c.response.URL)
+		}
+	}
+}
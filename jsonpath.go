@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON 解析 JSON 文本
+func decodeJSON(body string) (interface{}, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return nil, fmt.Errorf("响应体不是有效的 JSON: %w", err)
+	}
+	return data, nil
+}
+
+// lookupJSONPath 在解析后的 JSON 中按路径取值，支持 $.a.b、$.list[0] 形式
+func lookupJSONPath(data interface{}, path string) (interface{}, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON 路径必须以 $ 开头: %s", path)
+	}
+
+	cur := data
+	rest := path[1:]
+	for rest != "" {
+		var key string
+		index := -1
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("JSON 路径缺少 ]: %s", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if n, err := strconv.Atoi(inner); err == nil {
+				index = n
+			} else {
+				key = strings.Trim(inner, `'"`)
+			}
+		default:
+			return nil, fmt.Errorf("无效的 JSON 路径: %s", path)
+		}
+
+		if index >= 0 {
+			arr, ok := cur.([]interface{})
+			if !ok || index >= len(arr) {
+				return nil, fmt.Errorf("JSON 路径 %s 中的下标 %d 不存在", path, index)
+			}
+			cur = arr[index]
+			continue
+		}
+
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON 路径 %s 中的 %s 不存在", path, key)
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("JSON 路径 %s 中的 %s 不存在", path, key)
+		}
+	}
+	return cur, nil
+}
+
+// jsonValue 将 JSON 值转换为表达式中使用的值：整数转为 int，字符串保持原样，其余按 JSON 文本返回
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		if val == float64(int(val)) {
+			return int(val)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
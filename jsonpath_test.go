@@ -0,0 +1,38 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	data, err := decodeJSON(`{"data":{"user":{"role":"admin"},"items":[{"id":7},{"id":8}]},"ok":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]interface{}{
+		"$.data.user.role":          "admin",
+		"$.data.items[1].id":        float64(8),
+		"$['data']['user']['role']": "admin",
+		"$.ok":                      true,
+	} {
+		got, err := lookupJSONPath(data, path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v，期望 %v", path, got, want)
+		}
+	}
+
+	for _, path := range []string{"data.user", "$.data.missing", "$.data.items[5]", "$.data.items[0", "$.data.user.role.x"} {
+		if _, err := lookupJSONPath(data, path); err == nil {
+			t.Errorf("%s 应返回错误", path)
+		}
+	}
+	if _, err := decodeJSON("<html>"); err == nil {
+		t.Error("非 JSON 响应体应返回错误")
+	}
+}
@@ -0,0 +1,34 @@
+package sdk
+
+// LogLevel 日志详细程度，级别越高输出越多
+type LogLevel int
+
+const (
+	LogOff   LogLevel = iota // 不输出日志
+	LogInfo                  // 规则执行结果（跳过、不满足的条件等）
+	LogDebug                 // 另外输出请求行、响应状态、耗时、重试等
+	LogTrace                 // 另外输出完整的请求和响应体
+)
+
+// String 返回日志级别的名称
+func (l LogLevel) String() string {
+	switch l {
+	case LogOff:
+		return "off"
+	case LogInfo:
+		return "info"
+	case LogDebug:
+		return "debug"
+	case LogTrace:
+		return "trace"
+	}
+	return "unknown"
+}
+
+// verboseLevel SetVerbose 对应的日志级别，true 对应 LogDebug
+func verboseLevel(verbose bool) LogLevel {
+	if verbose {
+		return LogDebug
+	}
+	return LogOff
+}
@@ -0,0 +1,77 @@
+package sdk
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// captureLog 在 fn 执行期间捕获标准库 log 的输出
+func captureLog(t *testing.T, fn func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+	fn()
+	return buf.String()
+}
+
+func TestLogLevelControlsBodyOutput(t *testing.T) {
+	server := textServer(t, "response-body-marker")
+
+	for _, c := range []struct {
+		level       LogLevel
+		requestLine bool
+		body        bool
+	}{
+		{LogOff, false, false},
+		{LogInfo, false, false},
+		{LogDebug, true, false},
+		{LogTrace, true, true},
+	} {
+		client := NewHTTPClient(server.URL)
+		client.SetLogLevel(c.level)
+		output := captureLog(t, func() {
+			if _, err := client.ExecuteRequest(RequestOptions{Method: "POST", Path: "/submit", Body: "request-body-marker"}); err != nil {
+				t.Fatalf("%s: %v", c.level, err)
+			}
+		})
+		if got := strings.Contains(output, "/submit"); got != c.requestLine {
+			t.Errorf("%s: 日志包含请求行 = %v，期望 %v\n%s", c.level, got, c.requestLine, output)
+		}
+		if got := strings.Contains(output, "request-body-marker"); got != c.body {
+			t.Errorf("%s: 日志包含请求体 = %v，期望 %v\n%s", c.level, got, c.body, output)
+		}
+		if got := strings.Contains(output, "response-body-marker"); got != c.body {
+			t.Errorf("%s: 日志包含响应体 = %v，期望 %v\n%s", c.level, got, c.body, output)
+		}
+	}
+}
+
+func TestSetVerboseMapsToDebug(t *testing.T) {
+	engine := NewEngine(&POCConfig{}, "http://127.0.0.1")
+	engine.SetVerbose(true)
+	if engine.logLevel != LogDebug || engine.httpClient.logLevel != LogDebug {
+		t.Errorf("SetVerbose(true) 后级别为 %s/%s，期望 debug", engine.logLevel, engine.httpClient.logLevel)
+	}
+	engine.SetVerbose(false)
+	if engine.logLevel != LogOff || engine.httpClient.logLevel != LogOff {
+		t.Errorf("SetVerbose(false) 后级别为 %s/%s，期望 off", engine.logLevel, engine.httpClient.logLevel)
+	}
+}
+
+func TestLogLevelString(t *testing.T) {
+	for level, want := range map[LogLevel]string{
+		LogOff:       "off",
+		LogInfo:      "info",
+		LogDebug:     "debug",
+		LogTrace:     "trace",
+		LogLevel(42): "unknown",
+	} {
+		if got := level.String(); got != want {
+			t.Errorf("LogLevel(%d).String() = %q，期望 %q", int(level), got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sdk
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matcher 匹配器，参照 nuclei 的 matchers 定义
+// 同一匹配器中的多个 values 任一满足即视为匹配
+type Matcher struct {
+	Type   string   `yaml:"type"`   // word、regex、status、header
+	Values []string `yaml:"values"` // 匹配值
+	Part   string   `yaml:"part"`   // word/regex 的匹配位置：body（默认）、header、status、cookie、raw 或 RegisterPart 注册的名称
+	Negative bool   `yaml:"negative"` // 取反：匹配值都不满足时视为匹配，如响应中没有报错信息
+}
+
+// Match 判断响应是否满足匹配器，设置了 negative 时结果取反
+func (m *Matcher) Match(response *Response) (bool, error) {
+	if response == nil {
+		return false, nil
+	}
+
+	matched, err := m.match(response)
+	if err != nil {
+		return false, err
+	}
+	return matched != m.Negative, nil
+}
+
+// match 判断响应是否满足匹配器的任一匹配值
+func (m *Matcher) match(response *Response) (bool, error) {
+	switch strings.ToLower(m.Type) {
+	case "word":
+		text, err := responsePart(response, m.Part)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range m.Values {
+			if strings.Contains(text, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "regex":
+		text, err := responsePart(response, m.Part)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range m.Values {
+			re, err := regexp.Compile(convertRustRegex(v))
+			if err != nil {
+				return false, fmt.Errorf("%w: %w", ErrBadRegex, err)
+			}
+			if re.MatchString(text) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "status":
+		for _, v := range m.Values {
+			status, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return false, fmt.Errorf("无效的状态码: %s", v)
+			}
+			if response.Status == status {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "header":
+		// 值为 "Name" 时判断响应头是否存在，为 "Name: text" 时判断响应头的值是否包含 text
+		for _, v := range m.Values {
+			name, text, hasText := strings.Cut(v, ":")
+			values := response.Headers.Values(strings.TrimSpace(name))
+			if !hasText {
+				if values != nil {
+					return true, nil
+				}
+				continue
+			}
+			text = strings.TrimSpace(text)
+			for _, hv := range values {
+				if strings.Contains(hv, text) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("不支持的匹配器类型: %s", m.Type)
+}
+
+// evaluateMatchers 按 matchers-condition（and/or，默认 or）组合所有匹配器的结果
+func evaluateMatchers(matchers []Matcher, condition string, response *Response) (bool, error) {
+	and := strings.EqualFold(strings.TrimSpace(condition), "and")
+	for i := range matchers {
+		ok, err := matchers[i].Match(response)
+		if err != nil {
+			return false, err
+		}
+		if and && !ok {
+			return false, nil
+		}
+		if !and && ok {
+			return true, nil
+		}
+	}
+	return and, nil
+}
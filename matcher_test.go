@@ -0,0 +1,148 @@
+package sdk
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEvaluateMatchersConditions(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx/1.18.0")
+	response := &Response{Status: 200, Headers: headers, Body: "<title>phpMyAdmin</title>"}
+
+	word := Matcher{Type: "word", Values: []string{"phpMyAdmin"}}
+	status200 := Matcher{Type: "status", Values: []string{"200"}}
+	status403 := Matcher{Type: "status", Values: []string{"403"}}
+
+	tests := []struct {
+		name      string
+		matchers  []Matcher
+		condition string
+		want      bool
+	}{
+		{"and 全部满足", []Matcher{word, status200}, "and", true},
+		{"and 部分满足", []Matcher{word, status403}, "and", false},
+		{"or 部分满足", []Matcher{word, status403}, "or", true},
+		{"默认为 or", []Matcher{status403, word}, "", true},
+		{"or 都不满足", []Matcher{status403, {Type: "word", Values: []string{"wordpress"}}}, "or", false},
+	}
+	for _, tt := range tests {
+		got, err := evaluateMatchers(tt.matchers, tt.condition, response)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherTypes(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx/1.18.0")
+	response := &Response{Status: 302, Headers: headers, Body: "version 5.7.2"}
+
+	tests := []struct {
+		matcher Matcher
+		want    bool
+	}{
+		{Matcher{Type: "regex", Values: []string{`version \d+\.\d+`}}, true},
+		{Matcher{Type: "header", Values: []string{"Server"}}, true},
+		{Matcher{Type: "header", Values: []string{"Server: nginx"}}, true},
+		{Matcher{Type: "header", Values: []string{"X-Missing"}}, false},
+		{Matcher{Type: "word", Part: "header", Values: []string{"nginx"}}, true},
+		{Matcher{Type: "status", Values: []string{"200", "302"}}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.matcher.Match(response)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.matcher, err)
+		}
+		if got != tt.want {
+			t.Errorf("%+v = %v，期望 %v", tt.matcher, got, tt.want)
+		}
+	}
+
+	if _, err := (&Matcher{Type: "dsl"}).Match(response); err == nil {
+		t.Error("不支持的匹配器类型应返回错误")
+	}
+}
+
+func TestRuleWithMatchers(t *testing.T) {
+	server := textServer(t, "<title>phpMyAdmin</title>")
+	for condition, want := range map[string]bool{"and": false, "or": true} {
+		engine := NewEngine(mustLoadConfig(t, `
+name: matchers
+rules:
+  r0:
+    method: GET
+    path: /
+    matchers-condition: `+condition+`
+    matchers:
+      - type: word
+        values: ["phpMyAdmin"]
+      - type: status
+        values: ["403"]
+expression: r0()
+`), server.URL)
+		matched, err := engine.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != want {
+			t.Errorf("matchers-condition %s = %v，期望 %v", condition, matched, want)
+		}
+	}
+}
+
+func TestNegativeMatcher(t *testing.T) {
+	clean := &Response{Status: 200, Headers: make(http.Header), Body: "user list"}
+	failing := &Response{Status: 500, Headers: make(http.Header), Body: "SQL syntax error near 'x'"}
+	noError := Matcher{Type: "word", Values: []string{"SQL syntax", "ORA-"}, Negative: true}
+	status200 := Matcher{Type: "status", Values: []string{"200"}}
+
+	for _, c := range []struct {
+		name      string
+		response  *Response
+		matchers  []Matcher
+		condition string
+		want      bool
+	}{
+		{"无报错信息", clean, []Matcher{noError}, "", true},
+		{"有报错信息", failing, []Matcher{noError}, "", false},
+		{"and 组合", clean, []Matcher{status200, noError}, "and", true},
+		{"and 组合时取反失败", failing, []Matcher{status200, noError}, "and", false},
+		// 取反只作用于单个匹配器，不是对整个组合取反
+		{"or 组合", failing, []Matcher{{Type: "status", Values: []string{"500"}}, noError}, "or", true},
+	} {
+		got, err := evaluateMatchers(c.matchers, c.condition, c.response)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s = %v，期望 %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNegativeMatcherFromYAML(t *testing.T) {
+	server := textServer(t, "welcome")
+	engine := NewEngine(mustLoadConfig(t, `
+name: negative
+rules:
+  r0:
+    method: GET
+    path: /
+    matchers:
+      - type: word
+        values: ["welcome"]
+      - type: word
+        negative: true
+        values: ["error", "exception"]
+    matchers-condition: and
+expression: r0()
+`), server.URL)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Errorf("Execute() = %v, %v，期望匹配", matched, err)
+	}
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OOBProvider 带外（OOB）交互服务，用于检测无回显的 RCE、SSRF 等漏洞
+// 由使用者对接自己的回连平台（如 interactsh、DNSLog）
+type OOBProvider interface {
+	// Domain 返回回连平台的根域名，引擎会在其前面加上随机标识生成本次执行的回连域名
+	Domain() string
+	// Poll 查询随机标识为 token 的回连域名是否收到过请求
+	Poll(token string) (bool, error)
+}
+
+// oobTokenLength 回连域名随机标识的长度
+const oobTokenLength = 12
+
+// oobReceivedExpr 查询回连结果的表达式
+const oobReceivedExpr = "oob.received()"
+
+// SetOOBProvider 设置带外交互服务，设置后可以在规则中使用 {{oob}} 和 oob.received()
+func (e *ExpressionEvaluator) SetOOBProvider(provider OOBProvider) {
+	e.oob = provider
+}
+
+// newOOBDomain 生成回连域名并记录随机标识：{{oob}} 为完整域名，{{oob_token}} 为随机标识
+func (e *ExpressionEvaluator) newOOBDomain() (string, bool) {
+	if e.oob == nil {
+		return "", false
+	}
+	token := randomString(oobTokenLength)
+	domain := token + "." + strings.TrimPrefix(e.oob.Domain(), ".")
+	e.context["oob_token"] = token
+	e.context["oob"] = domain
+	return domain, true
+}
+
+// resetOOB 清除上一次执行的回连域名和随机标识，之后使用 {{oob}} 时重新生成
+// 否则上一次执行收到的回连会让本次执行误报
+func (e *ExpressionEvaluator) resetOOB() {
+	delete(e.context, "oob")
+	delete(e.context, "oob_token")
+}
+
+// evaluateOOBReceived 查询本次执行生成的回连域名是否收到过请求
+func (e *ExpressionEvaluator) evaluateOOBReceived() (bool, error) {
+	if e.oob == nil {
+		return false, fmt.Errorf("未设置 OOB 服务，无法使用 %s", oobReceivedExpr)
+	}
+	token, ok := e.context["oob_token"].(string)
+	if !ok {
+		return false, fmt.Errorf("使用 %s 前需要先在请求中使用 {{oob}}", oobReceivedExpr)
+	}
+	received, err := e.oob.Poll(token)
+	if err != nil {
+		return false, fmt.Errorf("查询 OOB 交互失败: %w", err)
+	}
+	return received, nil
+}
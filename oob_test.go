@@ -0,0 +1,148 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockOOB 记录收到回连的随机标识
+type mockOOB struct {
+	mu       sync.Mutex
+	received map[string]bool
+	polled   []string
+}
+
+func (m *mockOOB) Domain() string { return ".oob.test" }
+
+func (m *mockOOB) Poll(token string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.polled = append(m.polled, token)
+	return m.received[token], nil
+}
+
+// interact 模拟目标访问回连域名
+func (m *mockOOB) interact(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if token, ok := strings.CutSuffix(host, ".oob.test"); ok {
+		m.received[token] = true
+	}
+}
+
+const oobPOC = `
+name: blind-ssrf
+rules:
+  r0:
+    method: POST
+    path: /api/fetch
+    headers:
+      Content-Type: application/x-www-form-urlencoded
+    body:
+      - "url=http://{{oob}}/"
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /
+    headers:
+      X-Token: "{{oob_token}}"
+    expression: oob.received()
+expression: r0() && r1()
+`
+
+func TestOOBReceived(t *testing.T) {
+	for _, vulnerable := range []bool{true, false} {
+		oob := &mockOOB{received: map[string]bool{}}
+		var fetched, headerToken string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/fetch" {
+				u, err := url.Parse(r.FormValue("url"))
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				fetched = u.Hostname()
+				if vulnerable {
+					oob.interact(fetched)
+				}
+				return
+			}
+			headerToken = r.Header.Get("X-Token")
+		}))
+
+		engine := NewEngine(mustLoadConfig(t, oobPOC), server.URL)
+		engine.SetOOBProvider(oob)
+		matched, err := engine.Execute()
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != vulnerable {
+			t.Errorf("vulnerable = %v 时 matched = %v", vulnerable, matched)
+		}
+		token, _, _ := strings.Cut(fetched, ".")
+		if len(token) != oobTokenLength || fetched != token+".oob.test" {
+			t.Errorf("回连域名 = %q", fetched)
+		}
+		if headerToken != token || len(oob.polled) != 1 || oob.polled[0] != token {
+			t.Errorf("{{oob_token}} = %q, Poll 参数 = %v，期望都为 %q", headerToken, oob.polled, token)
+		}
+	}
+}
+
+func TestOOBReceivedErrors(t *testing.T) {
+	e := NewExpressionEvaluator()
+	if _, err := e.Evaluate("oob.received()", &Response{}, ""); err == nil {
+		t.Error("未设置 OOB 服务时应返回错误")
+	}
+	e.SetOOBProvider(&mockOOB{received: map[string]bool{}})
+	if _, err := e.Evaluate("oob.received()", &Response{}, ""); err == nil {
+		t.Error("未使用 {{oob}} 时应返回错误")
+	}
+}
+
+func TestOOBDomainFreshPerRun(t *testing.T) {
+	oob := &mockOOB{received: map[string]bool{}}
+	vulnerable := true
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/fetch" {
+			u, err := url.Parse(r.FormValue("url"))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fetched = append(fetched, u.Hostname())
+			if vulnerable {
+				oob.interact(u.Hostname())
+			}
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, oobPOC), server.URL)
+	engine.SetOOBProvider(oob)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("第一次 Execute() = %v, %v，期望收到回连", matched, err)
+	}
+
+	// 第二次执行时目标不再回连，第一次收到的回连不能导致误报
+	vulnerable = false
+	matched, err := engine.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched {
+		t.Error("第二次执行沿用了上一次的回连域名，导致误报")
+	}
+	if len(fetched) != 2 || fetched[0] == fetched[1] {
+		t.Errorf("两次执行的回连域名 = %q，期望各不相同", fetched)
+	}
+	if len(oob.polled) != 2 || oob.polled[0] == oob.polled[1] {
+		t.Errorf("Poll 参数 = %q，期望每次执行使用新的随机标识", oob.polled)
+	}
+}
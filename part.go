@@ -0,0 +1,107 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PartResolver 从响应中取出匹配器要匹配的部分
+type PartResolver func(response *Response) string
+
+var (
+	partResolversMu sync.RWMutex
+	partResolvers   = map[string]PartResolver{
+		"body":   func(r *Response) string { return r.Body },
+		"header": func(r *Response) string { return strings.Join(headerLines(r.Headers), "\n") },
+		"status": func(r *Response) string { return strconv.Itoa(r.Status) },
+		"cookie": func(r *Response) string { return strings.Join(r.Headers.Values("Set-Cookie"), "\n") },
+		"raw":    rawResponse,
+	}
+)
+
+// RegisterPart 注册匹配器的 part，注册后匹配器中可以使用 part: name，同名时覆盖内置的 part
+func RegisterPart(name string, resolver PartResolver) {
+	partResolversMu.Lock()
+	defer partResolversMu.Unlock()
+	partResolvers[strings.ToLower(name)] = resolver
+}
+
+// responsePart 按名称（不区分大小写）取出响应的对应部分，名称为空时取响应体
+// 内置 body、header（每行一个 "Name: value"）、status（状态码）、cookie（每行一个 Set-Cookie 的值）、
+// raw（状态行、响应头和响应体）
+func responsePart(response *Response, part string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(part))
+	if name == "" {
+		name = "body"
+	}
+	partResolversMu.RLock()
+	resolver, ok := partResolvers[name]
+	partResolversMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("不支持的匹配位置: %s", part)
+	}
+	return resolver(response), nil
+}
+
+// partFuncRegex 匹配带 part 前缀的字符串函数，如 header:contains('nginx')、raw:not_contains({{marker}})
+var partFuncRegex = regexp.MustCompile(`^([A-Za-z_][\w-]*):(contains|not_contains|startswith|endswith)\((?:['"]([^'"]*)['"]|(\{\{[^}]+\}\}))\)$`)
+
+// evaluatePartFunc 在响应的指定部分上执行 contains、not_contains、startswith、endswith，
+// part 与匹配器的 part 相同（body、header、status、cookie、raw 或 RegisterPart 注册的名称）
+func (e *ExpressionEvaluator) evaluatePartFunc(part, fn, literal, ref string) (bool, error) {
+	text, err := e.literalOrVariable(literal, ref)
+	if err != nil {
+		return false, err
+	}
+	if e.response == nil {
+		return fn == "not_contains", nil
+	}
+
+	value, err := responsePart(e.response, part)
+	if err != nil {
+		return false, err
+	}
+	switch fn {
+	case "contains":
+		return strings.Contains(value, text), nil
+	case "not_contains":
+		return !strings.Contains(value, text), nil
+	case "startswith":
+		return strings.HasPrefix(value, text), nil
+	}
+	return strings.HasSuffix(value, text), nil
+}
+
+// headerLines 返回 "Name: value" 形式的响应头，按名称排序，同名字段保持原有顺序
+func headerLines(headers http.Header) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		for _, v := range headers[name] {
+			lines = append(lines, name+": "+v)
+		}
+	}
+	return lines
+}
+
+// rawResponse 拼出原始响应：状态行、响应头、空行和响应体
+func rawResponse(r *Response) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP/1.1 %d %s\r\n", r.Status, r.StatusText)
+	for _, line := range headerLines(r.Headers) {
+		sb.WriteString(line + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	sb.WriteString(r.Body)
+	return sb.String()
+}
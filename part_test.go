@@ -0,0 +1,116 @@
+package sdk
+
+import (
+	"net/http"
+	"testing"
+)
+
+// partTestResponse 返回用于匹配位置测试的响应
+func partTestResponse() *Response {
+	headers := make(http.Header)
+	headers.Set("Server", "nginx")
+	headers.Add("Set-Cookie", "session=abc; HttpOnly")
+	headers.Add("Set-Cookie", "lang=zh")
+	return &Response{Status: 403, StatusText: "Forbidden", Headers: headers, Body: "<h1>denied</h1>"}
+}
+
+func TestResponsePart(t *testing.T) {
+	response := partTestResponse()
+	for part, want := range map[string]string{
+		"":       "<h1>denied</h1>",
+		"body":   "<h1>denied</h1>",
+		" BODY ": "<h1>denied</h1>",
+		"header": "Server: nginx\nSet-Cookie: session=abc; HttpOnly\nSet-Cookie: lang=zh",
+		"status": "403",
+		"cookie": "session=abc; HttpOnly\nlang=zh",
+		"raw":    "HTTP/1.1 403 Forbidden\r\nServer: nginx\r\nSet-Cookie: session=abc; HttpOnly\r\nSet-Cookie: lang=zh\r\n\r\n<h1>denied</h1>",
+	} {
+		got, err := responsePart(response, part)
+		if err != nil {
+			t.Fatalf("%q: %v", part, err)
+		}
+		if got != want {
+			t.Errorf("responsePart(%q) = %q，期望 %q", part, got, want)
+		}
+	}
+
+	if _, err := responsePart(response, "trailer"); err == nil {
+		t.Error("未注册的匹配位置期望返回错误")
+	}
+}
+
+func TestRegisterPart(t *testing.T) {
+	RegisterPart("Server-Header", func(r *Response) string { return r.Headers.Get("Server") })
+	t.Cleanup(func() {
+		partResolversMu.Lock()
+		delete(partResolvers, "server-header")
+		partResolversMu.Unlock()
+	})
+
+	response := partTestResponse()
+	if got, err := responsePart(response, "server-header"); err != nil || got != "nginx" {
+		t.Errorf("自定义匹配位置 = %q, %v", got, err)
+	}
+	matched, err := evaluateMatchers([]Matcher{{Type: "word", Part: "server-header", Values: []string{"nginx"}}}, "", response)
+	if err != nil || !matched {
+		t.Errorf("使用自定义匹配位置的匹配器 = %v, %v", matched, err)
+	}
+}
+
+func TestMatchersUseParts(t *testing.T) {
+	response := partTestResponse()
+	for _, c := range []struct {
+		matcher Matcher
+		want    bool
+	}{
+		{Matcher{Type: "word", Values: []string{"denied"}}, true},
+		{Matcher{Type: "word", Part: "header", Values: []string{"Server: nginx"}}, true},
+		{Matcher{Type: "word", Part: "header", Values: []string{"denied"}}, false},
+		{Matcher{Type: "regex", Part: "status", Values: []string{`^4\d\d$`}}, true},
+		{Matcher{Type: "word", Part: "cookie", Values: []string{"HttpOnly"}}, true},
+		{Matcher{Type: "word", Part: "cookie", Values: []string{"nginx"}}, false},
+		{Matcher{Type: "regex", Part: "raw", Values: []string{`403 Forbidden[\s\S]*denied`}}, true},
+	} {
+		got, err := evaluateMatchers([]Matcher{c.matcher}, "", response)
+		if err != nil {
+			t.Fatalf("%+v: %v", c.matcher, err)
+		}
+		if got != c.want {
+			t.Errorf("%+v = %v，期望 %v", c.matcher, got, c.want)
+		}
+	}
+
+	if _, err := evaluateMatchers([]Matcher{{Type: "word", Part: "unknown", Values: []string{"x"}}}, "", response); err == nil {
+		t.Error("未知的 part 期望返回错误")
+	}
+}
+
+func TestPartPrefixedFunctions(t *testing.T) {
+	e := NewExpressionEvaluator()
+	e.SetVariable("marker", "denied")
+	response := partTestResponse()
+
+	for expr, want := range map[string]bool{
+		"body:contains('denied')":                         true,
+		"body:contains('nginx')":                          false,
+		"header:contains('nginx')":                        true,
+		"header:contains('denied')":                       false,
+		"status:startswith('4')":                          true,
+		"status:endswith('4')":                            false,
+		"cookie:startswith('session=')":                   true,
+		"cookie:contains('Server')":                       false,
+		"raw:contains('403 Forbidden')":                   true,
+		"raw:endswith('</h1>')":                           true,
+		"raw:not_contains({{marker}})":                    false,
+		"header:not_contains({{marker}})":                 true,
+		"HEADER:contains('nginx') && body:contains('h1')": true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+
+	if _, err := e.Evaluate("trailer:contains('x')", response, ""); err == nil {
+		t.Error("未注册的 part 前缀期望返回错误")
+	}
+}
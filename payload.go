@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PayloadGenerator 载荷生成器，Next 依次返回载荷，返回 false 表示已生成完毕
+type PayloadGenerator interface {
+	Next() (string, bool)
+}
+
+// PayloadGeneratorFactory 根据参数创建生成器，每次执行规则都会创建新的生成器
+type PayloadGeneratorFactory func(args string) (PayloadGenerator, error)
+
+var (
+	payloadGeneratorsMu sync.RWMutex
+	payloadGenerators   = map[string]PayloadGeneratorFactory{
+		"range": newRangeGeneratorFromArgs,
+	}
+)
+
+// RegisterPayloadGenerator 注册载荷生成器，规则中通过 payload_generator: "名称:参数" 引用
+// 同名注册会覆盖已有的生成器
+func RegisterPayloadGenerator(name string, factory PayloadGeneratorFactory) {
+	payloadGeneratorsMu.Lock()
+	defer payloadGeneratorsMu.Unlock()
+	payloadGenerators[name] = factory
+}
+
+// newPayloadGenerator 解析 "名称:参数" 形式的引用并创建生成器
+func newPayloadGenerator(spec string) (PayloadGenerator, error) {
+	name, args, _ := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+
+	payloadGeneratorsMu.RLock()
+	factory, ok := payloadGenerators[name]
+	payloadGeneratorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未注册的载荷生成器: %s", name)
+	}
+	return factory(strings.TrimSpace(args))
+}
+
+// rangeGenerator 按步长生成 [start, end] 范围内的整数
+type rangeGenerator struct {
+	next, end, step int
+}
+
+// NewRangeGenerator 创建整数范围生成器，step 为 0 时按 1 处理
+func NewRangeGenerator(start, end, step int) PayloadGenerator {
+	if step == 0 {
+		step = 1
+	}
+	return &rangeGenerator{next: start, end: end, step: step}
+}
+
+func (g *rangeGenerator) Next() (string, bool) {
+	if (g.step > 0 && g.next > g.end) || (g.step < 0 && g.next < g.end) {
+		return "", false
+	}
+	v := g.next
+	g.next += g.step
+	return strconv.Itoa(v), true
+}
+
+// newRangeGeneratorFromArgs 解析 "1-100" 或 "1-100/2"（步长为 2）
+func newRangeGeneratorFromArgs(args string) (PayloadGenerator, error) {
+	bounds, stepStr, hasStep := strings.Cut(args, "/")
+	startStr, endStr, ok := strings.Cut(bounds, "-")
+	if !ok {
+		return nil, fmt.Errorf("无效的范围: %s", args)
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil {
+		return nil, fmt.Errorf("无效的范围起点: %s", startStr)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endStr))
+	if err != nil {
+		return nil, fmt.Errorf("无效的范围终点: %s", endStr)
+	}
+	step := 1
+	if hasStep {
+		step, err = strconv.Atoi(strings.TrimSpace(stepStr))
+		if err != nil || step <= 0 {
+			return nil, fmt.Errorf("无效的步长: %s", stepStr)
+		}
+	}
+	if start > end {
+		step = -step
+	}
+	return NewRangeGenerator(start, end, step), nil
+}
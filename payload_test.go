@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// collect 取出生成器的全部载荷
+func collect(gen PayloadGenerator) []string {
+	var payloads []string
+	for payload, ok := gen.Next(); ok; payload, ok = gen.Next() {
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+func TestRangeGenerator(t *testing.T) {
+	for args, want := range map[string][]string{
+		"1-5":     {"1", "2", "3", "4", "5"},
+		"0-10/5":  {"0", "5", "10"},
+		"3-1":     {"3", "2", "1"},
+		" 7 - 7 ": {"7"},
+	} {
+		gen, err := newPayloadGenerator("range:" + args)
+		if err != nil {
+			t.Errorf("range:%s: %v", args, err)
+			continue
+		}
+		if got := collect(gen); !reflect.DeepEqual(got, want) {
+			t.Errorf("range:%s = %v，期望 %v", args, got, want)
+		}
+	}
+
+	for _, spec := range []string{"range:1", "range:a-5", "range:1-5/0", "missing:1-5"} {
+		if _, err := newPayloadGenerator(spec); err == nil {
+			t.Errorf("%s 应返回错误", spec)
+		}
+	}
+}
+
+func TestRuleUsesPayloadGenerator(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		ids = append(ids, id)
+		if id == "7" {
+			w.Write([]byte("secret order"))
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: generator
+rules:
+  r0:
+    method: GET
+    path: /order?id={{payload}}
+    payload_generator: "range:1-10"
+    expression: response.body.contains('secret')
+expression: r0()
+`), server.URL)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	if want := []string{"1", "2", "3", "4", "5", "6", "7"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("请求的 id = %v，期望命中 7 后停止", ids)
+	}
+	if payload, _ := engine.evaluator.GetVariable("r0.payload"); payload != "7" {
+		t.Errorf("命中的 payload = %v，期望 7", payload)
+	}
+}
+
+// letterGenerator 依次生成参数中的每个字符
+type letterGenerator struct{ letters []rune }
+
+func (g *letterGenerator) Next() (string, bool) {
+	if len(g.letters) == 0 {
+		return "", false
+	}
+	letter := g.letters[0]
+	g.letters = g.letters[1:]
+	return string(letter), true
+}
+
+func TestRegisterPayloadGenerator(t *testing.T) {
+	RegisterPayloadGenerator("letters", func(args string) (PayloadGenerator, error) {
+		return &letterGenerator{letters: []rune(args)}, nil
+	})
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: custom-generator
+rules:
+  r0:
+    method: GET
+    path: /{{payload}}
+    payloads: ["x"]
+    payload_generator: "letters:abc"
+    expression: response.status == 404
+expression: r0()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || matched {
+		t.Fatalf("Execute() = %v, %v，期望不匹配", matched, err)
+	}
+	if want := []string{"/x", "/a", "/b", "/c"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("请求路径 = %v，期望先用 payloads 再用生成器", paths)
+	}
+}
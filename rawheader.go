@@ -0,0 +1,189 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+// rawHeadersKey 请求 context 中保存按原样发送的请求头名称（按发送顺序）
+type rawHeadersKey struct{}
+
+// parseRawHeaders 将 "Name: value" 形式的请求头逐行写入 header，名称保持原有大小写，返回按顺序排列的名称
+func parseRawHeaders(lines []string, header http.Header) ([]string, error) {
+	names := make([]string, 0, len(lines))
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("无效的原始请求头: %q", line)
+		}
+		// 直接写入 map 而不是 Header.Set，避免名称被规范化
+		if _, exists := header[name]; !exists {
+			names = append(names, name)
+		}
+		header[name] = append(header[name], strings.TrimSpace(value))
+	}
+	return names, nil
+}
+
+// rawHeaderTransport 请求带有原始请求头时自行建立连接并按顺序写出请求，其余请求交给 next
+// net/http 发送请求时会按名称排序请求头，无法保持顺序
+type rawHeaderTransport struct {
+	client *HTTPClient
+	next   http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *rawHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	names, ok := req.Context().Value(rawHeadersKey{}).([]string)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	return t.client.roundTripRaw(req, names)
+}
+
+// roundTripRaw 使用 HTTP/1.1 发送请求：先按顺序写出原始请求头，再写出其余请求头，连接不复用
+func (c *HTTPClient) roundTripRaw(req *http.Request, names []string) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Hostname()
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := c.dialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	var state *tls.ConnectionState
+	if req.URL.Scheme == "https" {
+		config := c.transport.TLSClientConfig.Clone()
+		config.ServerName = host
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s := tlsConn.ConnectionState()
+		state = &s
+		conn = tlsConn
+	}
+
+	// context 取消时关闭连接，中断读写
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	if err := writeRawRequest(conn, req, names); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	resp.TLS = state
+	resp.Body = &rawConnBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// writeRawRequest 写出请求行、请求头和请求体
+func writeRawRequest(w io.Writer, req *http.Request, names []string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+
+	raw := make(map[string]bool, len(names))
+	for _, name := range names {
+		raw[name] = true
+	}
+	hasHeader := func(name string) bool {
+		for k := range req.Header {
+			if strings.EqualFold(k, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasHeader("Host") {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		fmt.Fprintf(bw, "Host: %s\r\n", host)
+	}
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			fmt.Fprintf(bw, "%s: %s\r\n", name, v)
+		}
+	}
+	// 规则的普通请求头、Cookie、认证头等写在原始请求头之后
+	rest := make(http.Header)
+	for k, v := range req.Header {
+		if !raw[k] {
+			rest[k] = v
+		}
+	}
+	if err := rest.Write(bw); err != nil {
+		return err
+	}
+
+	chunked := len(req.TransferEncoding) > 0 && req.TransferEncoding[0] == "chunked"
+	if req.Body != nil && req.Body != http.NoBody && !hasHeader("Content-Length") && !hasHeader("Transfer-Encoding") {
+		if chunked {
+			bw.WriteString("Transfer-Encoding: chunked\r\n")
+		} else {
+			fmt.Fprintf(bw, "Content-Length: %d\r\n", req.ContentLength)
+		}
+	}
+	if !hasHeader("Connection") {
+		bw.WriteString("Connection: close\r\n")
+	}
+	bw.WriteString("\r\n")
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var body io.Writer = bw
+		var cw io.WriteCloser
+		if chunked {
+			cw = httputil.NewChunkedWriter(bw)
+			body = cw
+		}
+		_, err := io.Copy(body, req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		if cw != nil {
+			cw.Close()
+			bw.WriteString("\r\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// rawConnBody 关闭响应体时一并关闭连接
+type rawConnBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+// Close 关闭响应体和连接
+func (b *rawConnBody) Close() error {
+	b.stop()
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
@@ -0,0 +1,82 @@
+package sdk
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRawHeaders(t *testing.T) {
+	header := http.Header{}
+	names, err := parseRawHeaders([]string{"x-custom-HEADER: a", "  X-Trace :b ", "x-custom-HEADER: c"}, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"x-custom-HEADER", "X-Trace"}) {
+		t.Errorf("names = %v", names)
+	}
+	if !reflect.DeepEqual(header["x-custom-HEADER"], []string{"a", "c"}) || !reflect.DeepEqual(header["X-Trace"], []string{"b"}) {
+		t.Errorf("header = %v，名称应保持原有大小写", header)
+	}
+
+	for _, line := range []string{"no-colon", ": empty-name"} {
+		if _, err := parseRawHeaders([]string{line}, http.Header{}); err == nil {
+			t.Errorf("%q 期望返回错误", line)
+		}
+	}
+}
+
+func TestRawHeadersSentVerbatim(t *testing.T) {
+	requests := make(chan string, 1)
+	url := rawServer(t, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok", func(request string) {
+		requests <- request
+	})
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: raw-headers
+rules:
+  r0:
+    method: GET
+    path: /
+    raw_headers:
+      - "x-custom-HEADER: one"
+      - "aCCEPT: */*"
+    headers:
+      X-Normal: two
+    expression: response.body == 'ok'
+expression: r0()
+`), url)
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+
+	request := <-requests
+	lines := strings.Split(request, "\r\n")
+	// 原始请求头紧跟 Host，按配置的大小写和顺序写出
+	if len(lines) < 4 || !strings.HasPrefix(lines[1], "Host: ") || lines[2] != "x-custom-HEADER: one" || lines[3] != "aCCEPT: */*" {
+		t.Errorf("请求头未按原样发送:\n%s", request)
+	}
+	if !strings.Contains(request, "X-Normal: two\r\n") {
+		t.Errorf("普通请求头丢失:\n%s", request)
+	}
+	if strings.Contains(request, "X-Custom-Header") {
+		t.Errorf("原始请求头被规范化:\n%s", request)
+	}
+}
+
+func TestRawHeadersRejectInvalidLine(t *testing.T) {
+	if _, err := LoadConfigBytes([]byte(`
+name: bad-raw-header
+rules:
+  r0:
+    method: GET
+    path: /
+    raw_headers:
+      - "missing colon"
+    expression: response.status == 200
+expression: r0()
+`)); err == nil {
+		t.Error("缺少冒号的原始请求头期望加载失败")
+	}
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Result POC 执行结果，便于流水线以 JSON 形式消费
+type Result struct {
+	Name     string            `json:"name"`
+	CVEID    string            `json:"cve_id,omitempty"`
+	Category string            `json:"category,omitempty"` // 小写形式的类别
+	Severity Severity          `json:"severity"`
+	Source   string            `json:"source,omitempty"`  // 参考链接，对应配置中的 source
+	Summary  string            `json:"summary,omitempty"` // 一句话描述，对应配置中的 s1
+	Target   string            `json:"target"`
+	Matched  bool              `json:"matched"`
+	Rules    map[string]bool   `json:"rules"`
+	Skipped  []string          `json:"skipped,omitempty"` // 因前置条件不满足而未执行的规则
+	Score    int               `json:"score,omitempty"`   // 成功规则的权重之和
+	BlockedByWAF bool          `json:"blocked_by_waf,omitempty"` // 有响应命中 WAF 拦截页特征，未匹配可能是被拦截而非不存在漏洞
+	Outputs  map[string]string `json:"outputs,omitempty"`
+	Start    time.Time         `json:"start"`
+	Duration time.Duration     `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+	ErrorType string           `json:"error_type,omitempty"` // 目标不可达的原因：conn_refused、timeout、dns、tls
+}
+
+// RuleResult 单条规则的执行结果，用于调试时单独运行规则
+type RuleResult struct {
+	Name     string    `json:"name"`
+	DisplayName string `json:"display_name,omitempty"` // 规则配置中的 name
+	Description string `json:"description,omitempty"` // 规则配置中的 description
+	Matched  bool      `json:"matched"`
+	Skipped  bool      `json:"skipped,omitempty"` // 前置条件不满足，未发送请求
+	Payload  string    `json:"payload,omitempty"` // 命中时使用的 payload
+	Error    string    `json:"error,omitempty"`
+	Response *Response `json:"-"`
+}
+
+// GroupByCategory 按类别对结果分组，类别不区分大小写，没有类别的结果归入空字符串
+// 每组内保持原有顺序
+func GroupByCategory(results []Result) map[string][]Result {
+	groups := make(map[string][]Result)
+	for _, r := range results {
+		category := normalizeCategory(r.Category)
+		groups[category] = append(groups[category], r)
+	}
+	return groups
+}
+
+// normalizeCategory 统一类别的大小写和首尾空白
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// JSON 将结果序列化为 JSON，rules、outputs 等映射按键排序，相同的结果序列化后完全一致
+func (r Result) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -0,0 +1,193 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultJSONForMatchingRun(t *testing.T) {
+	server := textServer(t, "token=abc123")
+	engine := NewEngine(mustLoadConfig(t, `
+name: result-json
+cve_id: CVE-2024-0001
+rules:
+  r0:
+    method: GET
+    path: /
+    extract_cookie: response.body.extract('token=(?P<token>\w+)')
+    expression: response.body.contains('token=')
+expression: r0()
+`), server.URL)
+
+	matched, err := engine.Execute()
+	if err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+
+	data, err := engine.Result().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for field, want := range map[string]interface{}{
+		"name":    "result-json",
+		"cve_id":  "CVE-2024-0001",
+		"target":  server.URL,
+		"matched": true,
+	} {
+		if got[field] != want {
+			t.Errorf("%s = %v，期望 %v", field, got[field], want)
+		}
+	}
+	if rules, _ := got["rules"].(map[string]interface{}); rules["r0"] != true {
+		t.Errorf("rules = %v", got["rules"])
+	}
+	if outputs, _ := got["outputs"].(map[string]interface{}); outputs["token"] != "abc123" {
+		t.Errorf("outputs = %v", got["outputs"])
+	}
+	for _, field := range []string{"start", "duration"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("缺少字段 %s", field)
+		}
+	}
+	if _, ok := got["error"]; ok {
+		t.Errorf("成功执行时不应包含 error: %v", got["error"])
+	}
+}
+
+func TestResultSourceAndSummary(t *testing.T) {
+	server := textServer(t, "ok")
+	engine := NewEngine(mustLoadConfig(t, `
+name: source
+source: "https://nvd.nist.gov/vuln/detail/CVE-2024-0001"
+s1: "登录接口存在默认口令"
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+expression: r0()
+`), server.URL)
+
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	result := engine.Result()
+	if result.Source != "https://nvd.nist.gov/vuln/detail/CVE-2024-0001" || result.Summary != "登录接口存在默认口令" {
+		t.Errorf("Source = %q, Summary = %q", result.Source, result.Summary)
+	}
+
+	data, err := result.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["source"] != result.Source || got["summary"] != result.Summary {
+		t.Errorf("JSON 中 source = %v, summary = %v", got["source"], got["summary"])
+	}
+}
+
+func TestInvalidSourceRejected(t *testing.T) {
+	for _, source := range []string{"来源", "ftp://example.com/advisory", "https://"} {
+		_, err := LoadConfigBytes([]byte(`
+name: bad-source
+source: "` + source + `"
+rules:
+  r0:
+    method: GET
+    path: /
+`))
+		if err == nil {
+			t.Errorf("source %q 应被拒绝", source)
+		}
+	}
+}
+
+func TestGroupByCategory(t *testing.T) {
+	server := textServer(t, "ok")
+	var results []Result
+	for i, category := range []string{"RCE", "sqli", " rce ", "", "SQLi", "xss"} {
+		engine := NewEngine(mustLoadConfig(t, fmt.Sprintf(`
+name: poc-%d
+category: "%s"
+rules:
+  r0:
+    method: GET
+    path: /
+    expression: response.status == 200
+expression: r0()
+`, i, category)), server.URL)
+		if _, err := engine.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, engine.Result())
+	}
+
+	if results[0].Category != "rce" || results[2].Category != "rce" {
+		t.Errorf("类别应统一为小写并去掉空白: %q, %q", results[0].Category, results[2].Category)
+	}
+
+	groups := GroupByCategory(results)
+	names := func(rs []Result) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+	want := map[string][]string{
+		"rce":  {"poc-0", "poc-2"},
+		"sqli": {"poc-1", "poc-4"},
+		"xss":  {"poc-5"},
+		"":     {"poc-3"},
+	}
+	if len(groups) != len(want) {
+		t.Errorf("分组 = %v", groups)
+	}
+	for category, wantNames := range want {
+		if got := names(groups[category]); !reflect.DeepEqual(got, wantNames) {
+			t.Errorf("类别 %q = %v，期望 %v", category, got, wantNames)
+		}
+	}
+
+	// 手工构造的结果同样不区分大小写
+	manual := GroupByCategory([]Result{{Name: "a", Category: "Info-Leak"}, {Name: "b", Category: "info-leak"}})
+	if got := names(manual["info-leak"]); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("info-leak = %v", got)
+	}
+}
+
+func TestResultJSONDeterministic(t *testing.T) {
+	result := Result{
+		Name:    "deterministic",
+		Target:  "http://example.com",
+		Rules:   map[string]bool{"r3": true, "r1": false, "r2": true, "r0": true},
+		Outputs: map[string]string{"zeta": "1", "alpha": "2", "mid": "3", "beta": "4"},
+		Start:   time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+	}
+	first, err := result.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		again, _ := result.JSON()
+		if !bytes.Equal(first, again) {
+			t.Fatalf("多次序列化结果不同:\n%s\n%s", first, again)
+		}
+	}
+	if !bytes.Contains(first, []byte(`"rules":{"r0":true,"r1":false,"r2":true,"r3":true}`)) ||
+		!bytes.Contains(first, []byte(`"outputs":{"alpha":"2","beta":"4","mid":"3","zeta":"1"}`)) {
+		t.Errorf("映射未按键排序: %s", first)
+	}
+}
@@ -0,0 +1,94 @@
+package sdk
+
+import "strings"
+
+// Severity 漏洞等级，按 info < low < medium < high < critical 排序
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// severityAliases 等级名称及常见别名（不区分大小写）
+var severityAliases = map[string]Severity{
+	"info":          SeverityInfo,
+	"information":   SeverityInfo,
+	"informational": SeverityInfo,
+	"信息":            SeverityInfo,
+	"提示":            SeverityInfo,
+	"low":           SeverityLow,
+	"低":             SeverityLow,
+	"低危":            SeverityLow,
+	"medium":        SeverityMedium,
+	"moderate":      SeverityMedium,
+	"中":             SeverityMedium,
+	"中危":            SeverityMedium,
+	"high":          SeverityHigh,
+	"高":             SeverityHigh,
+	"高危":            SeverityHigh,
+	"critical":      SeverityCritical,
+	"严重":            SeverityCritical,
+	"紧急":            SeverityCritical,
+}
+
+// ParseSeverity 解析等级名称，无法识别时返回 SeverityUnknown
+func ParseSeverity(level string) Severity {
+	if s, ok := severityAliases[strings.ToLower(strings.TrimSpace(level))]; ok {
+		return s
+	}
+	return SeverityUnknown
+}
+
+// String 返回等级的英文名称
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// MarshalText 以名称形式序列化等级
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 从名称或别名解析等级
+func (s *Severity) UnmarshalText(text []byte) error {
+	*s = ParseSeverity(string(text))
+	return nil
+}
+
+// AtLeast 判断等级是否不低于 min
+func (s Severity) AtLeast(min Severity) bool {
+	return s >= min
+}
+
+// Severity 解析配置中的 level 字段
+func (c *POCConfig) Severity() Severity {
+	return ParseSeverity(c.Level)
+}
+
+// FilterBySeverity 过滤出等级不低于 min 的 POC 配置
+func FilterBySeverity(configs []*POCConfig, min Severity) []*POCConfig {
+	var filtered []*POCConfig
+	for _, config := range configs {
+		if config.Severity().AtLeast(min) {
+			filtered = append(filtered, config)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,64 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSeverityAliases(t *testing.T) {
+	for level, want := range map[string]Severity{
+		"critical": SeverityCritical,
+		"CRITICAL": SeverityCritical,
+		"严重":       SeverityCritical,
+		" high ":   SeverityHigh,
+		"高危":       SeverityHigh,
+		"moderate": SeverityMedium,
+		"低":        SeverityLow,
+		"info":     SeverityInfo,
+		"":         SeverityUnknown,
+		"whatever": SeverityUnknown,
+	} {
+		if got := ParseSeverity(level); got != want {
+			t.Errorf("ParseSeverity(%q) = %v，期望 %v", level, got, want)
+		}
+	}
+}
+
+func TestSeverityOrdering(t *testing.T) {
+	ordered := []Severity{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+	for i := 1; i < len(ordered); i++ {
+		if !ordered[i].AtLeast(ordered[i-1]) || ordered[i-1].AtLeast(ordered[i]) {
+			t.Errorf("%v 应高于 %v", ordered[i], ordered[i-1])
+		}
+	}
+
+	configs := []*POCConfig{{Name: "a", Level: "低危"}, {Name: "b", Level: "critical"}, {Name: "c", Level: "medium"}}
+	filtered := FilterBySeverity(configs, SeverityMedium)
+	if len(filtered) != 2 || filtered[0].Name != "b" || filtered[1].Name != "c" {
+		t.Errorf("FilterBySeverity = %v", filtered)
+	}
+}
+
+func TestResultCarriesSeverity(t *testing.T) {
+	server := textServer(t, "ok")
+	engine := NewEngine(mustLoadConfig(t, `
+name: severity
+level: 严重
+rules:
+  r0:
+    method: GET
+    path: /
+`), server.URL)
+	engine.Execute()
+
+	result := engine.Result()
+	if result.Severity != SeverityCritical {
+		t.Fatalf("Result().Severity = %v", result.Severity)
+	}
+	data, _ := json.Marshal(result)
+	var got struct{ Severity string }
+	json.Unmarshal(data, &got)
+	if got.Severity != "critical" {
+		t.Errorf("JSON 中的 severity = %q", got.Severity)
+	}
+}
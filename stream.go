@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"bytes"
+	"io"
+)
+
+// streamChunkSize 流式扫描每次读取的大小
+const streamChunkSize = 32 * 1024
+
+// scanMarkers 边读取边查找标记，不保留完整响应体，返回各标记是否出现以及读取的总字节数
+// 每次读取都保留上一块末尾的 len(最长标记)-1 个字节，跨越块边界的标记同样能找到
+func scanMarkers(r io.Reader, markers []string) (map[string]bool, int64, error) {
+	found := make(map[string]bool, len(markers))
+	maxLen := 0
+	for _, m := range markers {
+		if m == "" {
+			continue
+		}
+		found[m] = false
+		if len(m) > maxLen {
+			maxLen = len(m)
+		}
+	}
+
+	var total int64
+	var tail []byte
+	buf := make([]byte, streamChunkSize)
+	remaining := len(found)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			window := append(tail, buf[:n]...)
+			for m, ok := range found {
+				if !ok && bytes.Contains(window, []byte(m)) {
+					found[m] = true
+					remaining--
+				}
+			}
+			if keep := maxLen - 1; keep > 0 && len(window) > keep {
+				tail = append(tail[:0], window[len(window)-keep:]...)
+			} else if keep > 0 {
+				tail = window
+			}
+		}
+		if err == io.EOF {
+			return found, total, nil
+		}
+		if err != nil {
+			return found, total, err
+		}
+		if remaining == 0 {
+			// 所有标记都已找到，读完剩余内容以便连接复用，但不再查找
+			n, err := io.Copy(io.Discard, r)
+			return found, total + n, err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScanMarkersAcrossChunks(t *testing.T) {
+	// 标记跨越两次读取的边界
+	body := strings.Repeat("a", streamChunkSize-3) + "MARKER" + strings.Repeat("b", 100)
+	found, size, err := scanMarkers(strings.NewReader(body), []string{"MARKER", "missing", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found["MARKER"] || found["missing"] {
+		t.Errorf("found = %v", found)
+	}
+	if _, ok := found[""]; ok {
+		t.Error("空标记不应参与扫描")
+	}
+	if size != int64(len(body)) {
+		t.Errorf("size = %d，期望 %d", size, len(body))
+	}
+}
+
+func TestStreamMarkersOnLargeBody(t *testing.T) {
+	const size = 8 << 20
+	body := strings.Repeat("x", size-64) + "root:x:0:0:root:/root:/bin/bash" + strings.Repeat("y", 32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: stream
+rules:
+  r0:
+    method: GET
+    path: /
+    stream_markers: ["root:x:0:0", "<html"]
+    expression: response.body.contains('root:x:0:0') && response.body.not_contains('<html')
+expression: r0()
+`), server.URL)
+
+	rr, err := engine.ExecuteRule("r0")
+	if err != nil || !rr.Matched {
+		t.Fatalf("ExecuteRule(r0) = %+v, %v", rr, err)
+	}
+	resp := rr.Response
+	if !resp.Streamed || resp.Body != "" || resp.BodySize != int64(len(body)) {
+		t.Errorf("Streamed = %v, len(Body) = %d, BodySize = %d", resp.Streamed, len(resp.Body), resp.BodySize)
+	}
+
+	e := NewExpressionEvaluator()
+	if _, err := e.Evaluate("response.body.contains('not-scanned')", resp, ""); err == nil {
+		t.Error("查询未扫描的标记应返回错误")
+	}
+}
@@ -0,0 +1,52 @@
+package sdk
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// randChars {{rand:N}} 使用的字符集
+const randChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// generateTemplateValue 生成内置模板变量的值：rand:N（N 位随机小写字母和数字）、uuid（v4）、timestamp（Unix 秒）
+func generateTemplateValue(name string) (string, bool) {
+	switch {
+	case name == "uuid":
+		return newUUID(), true
+	case name == "timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case strings.HasPrefix(name, "rand:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(name, "rand:"))
+		if err != nil || n <= 0 {
+			return "", false
+		}
+		return randomString(n), true
+	}
+	return "", false
+}
+
+// randomString 生成 n 位随机小写字母和数字
+func randomString(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	for i, b := range buf {
+		buf[i] = randChars[int(b)%len(randChars)]
+	}
+	return string(buf)
+}
+
+// newUUID 生成 v4 UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,129 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGenerateTemplateValue(t *testing.T) {
+	if v, ok := generateTemplateValue("rand:8"); !ok || !regexp.MustCompile(`^[a-z0-9]{8}$`).MatchString(v) {
+		t.Errorf("rand:8 = %q, %v", v, ok)
+	}
+	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if v, ok := generateTemplateValue("uuid"); !ok || !uuidRegex.MatchString(v) {
+		t.Errorf("uuid = %q, %v", v, ok)
+	}
+	v, ok := generateTemplateValue("timestamp")
+	ts, err := strconv.ParseInt(v, 10, 64)
+	if !ok || err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("timestamp = %q, %v", v, ok)
+	}
+	for _, name := range []string{"rand:0", "rand:x", "random", "session"} {
+		if _, ok := generateTemplateValue(name); ok {
+			t.Errorf("%s 不是内置模板", name)
+		}
+	}
+}
+
+func TestTemplateValuesSharedAcrossRules(t *testing.T) {
+	seen := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen["nonce"] = append(seen["nonce"], r.URL.Query().Get("nonce"))
+		seen["uuid"] = append(seen["uuid"], r.Header.Get("X-Request-Id"))
+		seen["ts"] = append(seen["ts"], r.FormValue("ts"))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: shared-nonce
+rules:
+  r0:
+    method: POST
+    path: /form?nonce={{rand:8}}
+    headers:
+      X-Request-Id: "{{uuid}}"
+      Content-Type: application/x-www-form-urlencoded
+    body:
+      - "ts={{timestamp}}"
+    expression: response.status == 200
+  r1:
+    method: POST
+    path: /submit?nonce={{rand:8}}
+    headers:
+      X-Request-Id: "{{uuid}}"
+      Content-Type: application/x-www-form-urlencoded
+    body:
+      - "ts={{timestamp}}"
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+
+	if matched, err := engine.Execute(); err != nil || !matched {
+		t.Fatalf("Execute() = %v, %v", matched, err)
+	}
+	for name, values := range seen {
+		if len(values) != 2 || values[0] == "" || values[0] != values[1] || values[0][0] == '{' {
+			t.Errorf("%s 在两条规则中应相同且已生成: %q", name, values)
+		}
+	}
+
+	// Reset 后重新生成
+	first := seen["nonce"][0]
+	seen = map[string][]string{}
+	engine.Reset()
+	if _, err := engine.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if seen["nonce"][0] == first {
+		t.Errorf("Reset 后 {{rand:8}} 应重新生成，仍为 %q", first)
+	}
+}
+
+func TestTemplateValuesRegeneratedPerRun(t *testing.T) {
+	var nonces, uuids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, r.URL.Query().Get("nonce"))
+		uuids = append(uuids, r.Header.Get("X-Request-Id"))
+	}))
+	defer server.Close()
+
+	engine := NewEngine(mustLoadConfig(t, `
+name: per-run-nonce
+rules:
+  r0:
+    method: GET
+    path: /a?nonce={{rand:8}}
+    headers:
+      X-Request-Id: "{{uuid}}"
+    expression: response.status == 200
+  r1:
+    method: GET
+    path: /b?nonce={{rand:8}}
+    headers:
+      X-Request-Id: "{{uuid}}"
+    expression: response.status == 200
+expression: r0() && r1()
+`), server.URL)
+	engine.evaluator.SetVariable("user", "alice")
+
+	// 同一引擎执行两次，不调用 Reset
+	for i := 0; i < 2; i++ {
+		if matched, err := engine.Execute(); err != nil || !matched {
+			t.Fatalf("第 %d 次 Execute() = %v, %v", i+1, matched, err)
+		}
+	}
+	if len(nonces) != 4 || nonces[0] != nonces[1] || nonces[2] != nonces[3] {
+		t.Fatalf("同一次执行中的随机值应相同: %q", nonces)
+	}
+	if nonces[0] == nonces[2] || uuids[0] == uuids[2] {
+		t.Errorf("两次执行应使用不同的模板值: nonce = %q, uuid = %q", nonces, uuids)
+	}
+	// 其余变量不受影响
+	if v, ok := engine.evaluator.GetVariable("user"); !ok || v != "alice" {
+		t.Errorf("普通变量被清除: %v, %v", v, ok)
+	}
+}
@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nowExpr 表达式中的当前时间
+const nowExpr = "now()"
+
+// timeLayouts 时间比较时依次尝试的格式：RFC3339（证书时间、now()）和 HTTP 日期（Date、Expires、Last-Modified）
+var timeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC850,
+	time.ANSIC,
+}
+
+// timeOffsetRegex 匹配带时长的时间运算，如 now() + 30d、response.tls.not_after - 12h
+var timeOffsetRegex = regexp.MustCompile(`^(.+?)\s*([+-])\s*(\d+)([smhd])$`)
+
+// parseTime 按 timeLayouts 解析时间
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseDurationLiteral 解析时长字面量：数字加单位 s、m、h、d
+func parseDurationLiteral(n, unit string) (time.Duration, error) {
+	v, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s%s", n, unit)
+	}
+	d := time.Duration(v)
+	switch unit {
+	case "s":
+		return d * time.Second, nil
+	case "m":
+		return d * time.Minute, nil
+	case "h":
+		return d * time.Hour, nil
+	}
+	return d * 24 * time.Hour, nil
+}
+
+// evaluateTime 将表达式求值为时间，支持 now()、可解析为时间的取值以及加减时长
+func (e *ExpressionEvaluator) evaluateTime(expr string) (time.Time, bool) {
+	expr = strings.TrimSpace(expr)
+	if matches := timeOffsetRegex.FindStringSubmatch(expr); matches != nil {
+		base, ok := e.evaluateTime(matches[1])
+		if !ok {
+			return time.Time{}, false
+		}
+		d, err := parseDurationLiteral(matches[3], matches[4])
+		if err != nil {
+			return time.Time{}, false
+		}
+		if matches[2] == "-" {
+			d = -d
+		}
+		return base.Add(d), true
+	}
+
+	if expr == nowExpr {
+		return time.Now(), true
+	}
+	val, err := e.evaluateValue(expr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parseTime(fmt.Sprintf("%v", val))
+}
+
+// compareTimeValues 两侧都能求值为时间时按时间先后比较
+func (e *ExpressionEvaluator) compareTimeValues(left, right string) (int, bool) {
+	leftTime, ok := e.evaluateTime(left)
+	if !ok {
+		return 0, false
+	}
+	rightTime, ok := e.evaluateTime(right)
+	if !ok {
+		return 0, false
+	}
+	return leftTime.Compare(rightTime), true
+}
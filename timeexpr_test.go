@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// certResponse 返回对端证书有效期截止到 notAfter 的 HTTPS 响应
+func certResponse(notAfter time.Time) *Response {
+	cert := &x509.Certificate{NotBefore: notAfter.Add(-365 * 24 * time.Hour), NotAfter: notAfter}
+	return &Response{
+		Status:  200,
+		Headers: make(http.Header),
+		TLS:     &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}
+}
+
+func TestCertificateExpiryComparison(t *testing.T) {
+	e := NewExpressionEvaluator()
+	expired := certResponse(time.Now().Add(-48 * time.Hour))
+	valid := certResponse(time.Now().Add(90 * 24 * time.Hour))
+	expiringSoon := certResponse(time.Now().Add(10 * 24 * time.Hour))
+
+	for _, c := range []struct {
+		name     string
+		response *Response
+		expr     string
+		want     bool
+	}{
+		{"过期证书", expired, "response.tls.not_after < now()", true},
+		{"有效证书", valid, "response.tls.not_after < now()", false},
+		{"有效证书", valid, "response.tls.not_after >= now()", true},
+		{"30 天内过期", expiringSoon, "response.tls.not_after < now() + 30d", true},
+		{"30 天内过期", valid, "response.tls.not_after < now() + 30d", false},
+		{"两天前过期", expired, "response.tls.not_after > now() - 72h", true},
+		{"两天前过期", expired, "response.tls.not_after > now() - 1d", false},
+		{"早于起始时间", valid, "response.tls.not_before < response.tls.not_after", true},
+	} {
+		if got := evalExpr(t, e, c.expr, c.response); got != c.want {
+			t.Errorf("%s: %s = %v，期望 %v", c.name, c.expr, got, c.want)
+		}
+	}
+}
+
+func TestHeaderTimeComparison(t *testing.T) {
+	e := NewExpressionEvaluator()
+	response := &Response{Status: 200, Headers: make(http.Header)}
+	response.Headers.Set("Expires", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	response.Headers.Set("Last-Modified", "Mon, 15 Jan 2024 08:00:00 GMT")
+
+	for expr, want := range map[string]bool{
+		"response.headers.get('Expires') < now()":                         true,
+		"response.headers.get('Expires') > now() - 2h":                    true,
+		"response.headers.get('Last-Modified') > '2024-01-01T00:00:00Z'":  true,
+		"response.headers.get('Last-Modified') < '2024-01-15T07:59:59Z'":  false,
+		"response.headers.get('Last-Modified') <= '2024-01-15T08:00:00Z'": true,
+	} {
+		if got := evalExpr(t, e, expr, response); got != want {
+			t.Errorf("%s = %v，期望 %v", expr, got, want)
+		}
+	}
+}
+
+func TestParseDurationLiteral(t *testing.T) {
+	for _, c := range []struct {
+		n, unit string
+		want    time.Duration
+	}{
+		{"30", "s", 30 * time.Second},
+		{"5", "m", 5 * time.Minute},
+		{"12", "h", 12 * time.Hour},
+		{"2", "d", 48 * time.Hour},
+	} {
+		if got, err := parseDurationLiteral(c.n, c.unit); err != nil || got != c.want {
+			t.Errorf("parseDurationLiteral(%s, %s) = %v, %v，期望 %v", c.n, c.unit, got, err, c.want)
+		}
+	}
+}